@@ -0,0 +1,956 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// DeploymentNameLabel is set on the Deployment created for a MyKind and on
+// its pods, and is used as the Deployment's selector.
+// The mutating webhook also sets it on the MyKind itself.
+const DeploymentNameLabel = "example-controller.jetstack.io/deployment-name"
+
+// ComponentLabel is set on the Deployment created for each component of a
+// MyKind and on its pods, to the name of the component.
+const ComponentLabel = "example-controller.jetstack.io/component"
+
+// CanaryLabel is set to "true" on the canary Deployment created during a
+// canary rollout of a MyKind and on its pods, and is part of its selector.
+const CanaryLabel = "example-controller.jetstack.io/canary"
+
+// CanaryDeploymentSuffix is appended to spec.deployment.name to give the
+// name of the canary Deployment created during a canary rollout.
+const CanaryDeploymentSuffix = "-canary"
+
+// ColorLabel is set to the colour of each of the Deployments created for a
+// MyKind that is rolled out with a blue/green rollout and on their pods, and
+// is part of their selectors.
+const ColorLabel = "example-controller.jetstack.io/color"
+
+// PreviewServiceSuffix is appended to spec.deployment.name to give the name
+// of the preview Service created for a blue/green rollout.
+const PreviewServiceSuffix = "-preview"
+
+// PromoteAnnotation promotes a rollout of a MyKind when set to "true" on it.
+// The current step of a canary rollout is promoted once its replicas are
+// available, skipping any remaining pause and manual promotion of the step,
+// and the preview Deployment of a blue/green rollout is promoted once its
+// replicas are available. It is removed by the controller once acted upon,
+// or if no rollout is in progress.
+const PromoteAnnotation = "example-controller.jetstack.io/promote"
+
+// RollbackToAnnotation rolls back the spec of a MyKind to the revision with
+// the number it is set to, in the same way as spec.rollbackTo. It is removed
+// by the controller once acted upon.
+const RollbackToAnnotation = "example-controller.jetstack.io/rollback-to"
+
+// PausedAnnotation pauses reconciliation of a MyKind when set to "true" on
+// it, in the same way as setting spec.paused.
+const PausedAnnotation = "example-controller.jetstack.io/paused"
+
+// MyKindSpec defines the desired state of MyKind
+type MyKindSpec struct {
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// Deployment describes the workload resource that the controller
+	// should create for this MyKind resource. Despite its name, it is used
+	// for each of the kinds of workload given by spec.workloadKind.
+	Deployment DeploymentSpec `json:"deployment"`
+
+	// WorkloadKind is the kind of workload resource that the controller
+	// should create to run the pods described by spec.deployment.
+	// When it is changed, the workload of the previous kind is deleted once
+	// the workload of the new kind has been rolled out.
+	// If not specified, a Deployment will be created.
+	// +optional
+	WorkloadKind WorkloadKind `json:"workloadKind,omitempty"`
+
+	// Components are additional workloads that are run alongside the one
+	// described by spec.deployment, such as the worker tier of an
+	// application whose web tier is spec.deployment. Each component is run
+	// as a Deployment, whatever spec.workloadKind is.
+	// The Service, Ingress, PodDisruptionBudget and HorizontalPodAutoscaler
+	// only apply to the workload described by spec.deployment.
+	// +optional
+	Components []ComponentSpec `json:"components,omitempty"`
+
+	// Rollout describes how changes to the pod template of the workload
+	// described by spec.deployment are rolled out.
+	// If not specified, they are rolled out by the workload itself.
+	// +optional
+	Rollout *RolloutSpec `json:"rollout,omitempty"`
+
+	// HealthCheck describes when the workload described by spec.deployment
+	// is considered to have failed, in addition to a Deployment exceeding
+	// its progress deadline, and what happens when it does.
+	// If not specified, the MyKind is only marked as Degraded when its
+	// Deployment exceeds its progress deadline.
+	// +optional
+	HealthCheck *HealthCheckSpec `json:"healthCheck,omitempty"`
+
+	// Service describes the Service resource that the controller should
+	// create in front of the Deployment.
+	// If not specified, no Service will be created.
+	// +optional
+	Service *ServiceSpec `json:"service,omitempty"`
+
+	// Ingress describes the Ingress resource that the controller should
+	// create to route HTTP traffic to the Service.
+	// spec.service must be specified if this is set.
+	// If not specified, no Ingress will be created.
+	// +optional
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+
+	// Autoscaling describes the HorizontalPodAutoscaler that the controller
+	// should create to scale the Deployment.
+	// If not specified, no HorizontalPodAutoscaler will be created and the
+	// Deployment will be scaled to spec.deployment.replicas.
+	// +optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+
+	// Schedules scale the workload, and optionally the Deployments of the
+	// components, to a different number of replicas for periods of time,
+	// such as overnight. While a schedule is active its replica counts are
+	// used in place of spec.deployment.replicas and the replica counts of
+	// the components it lists. If more than one schedule is active, the
+	// first of them is used.
+	// May not be specified with spec.autoscaling, or when
+	// spec.workloadKind is DaemonSet.
+	// +optional
+	Schedules []ScaleSchedule `json:"schedules,omitempty"`
+
+	// DisruptionBudget describes the PodDisruptionBudget that the
+	// controller should create to limit voluntary disruptions of the
+	// Deployment's pods.
+	// If not specified, no PodDisruptionBudget will be created.
+	// +optional
+	DisruptionBudget *DisruptionBudgetSpec `json:"disruptionBudget,omitempty"`
+
+	// DeletionPolicy determines what happens to the Deployment when this
+	// MyKind resource is deleted.
+	// If not specified, the Deployment will be deleted.
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// AdoptionPolicy determines whether the controller may take control of
+	// a Deployment named spec.deployment.name that it did not create.
+	// If not specified, existing Deployments will never be adopted.
+	// +optional
+	AdoptionPolicy AdoptionPolicy `json:"adoptionPolicy,omitempty"`
+
+	// Paused stops the controller from creating, updating or deleting any
+	// of the resources managed for this MyKind resource. Its status
+	// continues to be updated.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// RevisionHistoryLimit is the number of ControllerRevisions recording
+	// previous specs of this MyKind resource that are kept, in addition to
+	// that of the current spec.
+	// If not specified, it will be defaulted to 10.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// RollbackTo restores the spec recorded in a previous revision of this
+	// MyKind resource. spec.paused, spec.revisionHistoryLimit and
+	// spec.rollbackTo are not recorded in revisions, and are left as they
+	// are by a rollback. It is removed by the controller once acted upon,
+	// and is not acted upon while reconciliation is paused.
+	// +optional
+	RollbackTo *RollbackConfig `json:"rollbackTo,omitempty"`
+}
+
+// RollbackConfig describes a rollback to a previous revision of a MyKind.
+type RollbackConfig struct {
+	// Revision is the number of the revision to roll back to. If it is 0,
+	// the spec is rolled back to the latest revision that differs from the
+	// current spec.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Revision int64 `json:"revision,omitempty"`
+}
+
+// WorkloadKind is a kind of workload resource that may be created for a
+// MyKind resource.
+// +kubebuilder:validation:Enum=Deployment;StatefulSet;DaemonSet
+type WorkloadKind string
+
+const (
+	// WorkloadKindDeployment runs the pods with a Deployment.
+	WorkloadKindDeployment WorkloadKind = "Deployment"
+
+	// WorkloadKindStatefulSet runs the pods with a StatefulSet, giving each
+	// of them a stable identity. spec.service must be set and headless, as
+	// the Service is used as the governing service of the StatefulSet.
+	WorkloadKindStatefulSet WorkloadKind = "StatefulSet"
+
+	// WorkloadKindDaemonSet runs one pod on each node with a DaemonSet.
+	// spec.deployment.replicas is ignored, and spec.autoscaling and the
+	// ScaleToZero deletion policy may not be used.
+	WorkloadKindDaemonSet WorkloadKind = "DaemonSet"
+)
+
+// ComponentSpec describes a component of a MyKind resource, which is run as
+// its own Deployment.
+type ComponentSpec struct {
+	// Name identifies the component within the MyKind resource, and is set
+	// as the component label of its Deployment and pods.
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+
+	// Deployment describes the Deployment created for the component.
+	// Its name must differ from spec.deployment.name and from the names of
+	// the Deployments of the other components.
+	Deployment DeploymentSpec `json:"deployment"`
+}
+
+// RolloutSpec describes how changes to the pod template of the workload of
+// a MyKind resource are rolled out.
+type RolloutSpec struct {
+	// Canary rolls out a new pod template by running it in a canary
+	// Deployment alongside the stable Deployment named
+	// spec.deployment.name, which keeps running the previous pod template.
+	// The replicas are shifted to the canary Deployment step by step, and
+	// once the last step is complete the stable Deployment is updated to
+	// the new pod template and the canary Deployment is deleted.
+	// The pods of the canary Deployment have the labels of the stable pods,
+	// so they are selected by the Service and PodDisruptionBudget.
+	// It may only be used if spec.workloadKind is Deployment, and not
+	// together with spec.autoscaling.
+	// +optional
+	Canary *CanaryStrategy `json:"canary,omitempty"`
+
+	// BlueGreen rolls out a new pod template by running it in a complete
+	// preview Deployment alongside the active Deployment, and switching the
+	// Service over to the preview Deployment when it is promoted.
+	// The two Deployments are named spec.deployment.name followed by
+	// '-blue' and '-green', and a preview Service that routes traffic to
+	// the preview Deployment is created alongside the Service.
+	// spec.service must be specified. It may only be used if
+	// spec.workloadKind is Deployment, and not together with
+	// spec.autoscaling or canary.
+	// +optional
+	BlueGreen *BlueGreenStrategy `json:"blueGreen,omitempty"`
+}
+
+// CanaryStrategy describes a canary rollout.
+type CanaryStrategy struct {
+	// Steps are the steps of the rollout, which are completed in order.
+	// +kubebuilder:validation:MinItems=1
+	Steps []CanaryStep `json:"steps"`
+
+	// Abort stops a rollout that is in progress, deleting the canary
+	// Deployment and scaling the stable Deployment back up to all of the
+	// replicas. While it is set, changes to the pod template are not rolled
+	// out. When it is unset, the rollout starts again from the first step.
+	// +optional
+	Abort bool `json:"abort,omitempty"`
+}
+
+// CanaryStep describes a step of a canary rollout. Exactly one of weight
+// and replicas must be specified.
+// The step is complete once the canary replicas are available, any pause
+// has elapsed and, if manual promotion is required, the MyKind has been
+// promoted with the PromoteAnnotation.
+type CanaryStep struct {
+	// Weight is the percentage of spec.deployment.replicas, rounded up,
+	// that are run by the canary Deployment during the step. The rest are
+	// run by the stable Deployment.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight *int32 `json:"weight,omitempty"`
+
+	// Replicas is the number of the replicas that are run by the canary
+	// Deployment during the step, up to spec.deployment.replicas. The rest
+	// are run by the stable Deployment.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Pause is how long to wait once the canary replicas are available
+	// before the step is complete.
+	// +optional
+	Pause *metav1.Duration `json:"pause,omitempty"`
+
+	// ManualPromotion keeps the rollout at the step once the canary
+	// replicas are available and any pause has elapsed, until the MyKind is
+	// promoted with the PromoteAnnotation.
+	// +optional
+	ManualPromotion bool `json:"manualPromotion,omitempty"`
+}
+
+// BlueGreenStrategy describes a blue/green rollout.
+type BlueGreenStrategy struct {
+	// AutoPromote promotes the preview Deployment as soon as all of its
+	// replicas are available. If not set, it is promoted by setting the
+	// PromoteAnnotation on the MyKind.
+	// +optional
+	AutoPromote bool `json:"autoPromote,omitempty"`
+
+	// ScaleDownDelay is how long the previously active Deployment is kept
+	// after a promotion before it is deleted, so that traffic can be
+	// switched back to it by reverting the pod template.
+	// If not specified, it will be defaulted to 30 seconds.
+	// +optional
+	ScaleDownDelay *metav1.Duration `json:"scaleDownDelay,omitempty"`
+}
+
+// HealthCheckSpec describes the health check of the workload of a MyKind.
+type HealthCheckSpec struct {
+	// MinReadyPercentage is the percentage of the desired replicas of the
+	// workload that must be ready, below which the MyKind is marked as
+	// Degraded. It should allow for the replicas that are unavailable
+	// during a rolling update. It is only checked once a revision of the
+	// MyKind has been rolled out, so that the MyKind is not marked as
+	// Degraded while its pods first start.
+	// If not specified, the number of ready replicas is not checked.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	MinReadyPercentage *int32 `json:"minReadyPercentage,omitempty"`
+
+	// AutoRollback rolls back the spec of the MyKind to the revision given
+	// by status.lastKnownGoodRevision when its workload fails the health
+	// check, in the same way as spec.rollbackTo.
+	// +optional
+	AutoRollback bool `json:"autoRollback,omitempty"`
+}
+
+// DeploymentColor is the colour of one of the two Deployments of a
+// blue/green rollout.
+// +kubebuilder:validation:Enum=blue;green
+type DeploymentColor string
+
+const (
+	// DeploymentColorBlue is the colour of the Deployment named
+	// spec.deployment.name followed by '-blue'.
+	DeploymentColorBlue DeploymentColor = "blue"
+
+	// DeploymentColorGreen is the colour of the Deployment named
+	// spec.deployment.name followed by '-green'.
+	DeploymentColorGreen DeploymentColor = "green"
+)
+
+// DeletionPolicy describes what happens to the Deployment created for a
+// MyKind resource when the MyKind resource is deleted.
+// +kubebuilder:validation:Enum=Delete;Orphan;ScaleToZero
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the Deployment along with the MyKind.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+
+	// DeletionPolicyOrphan leaves the Deployment running, and removes the
+	// owner reference that would otherwise cause it to be garbage
+	// collected.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+
+	// DeletionPolicyScaleToZero orphans the Deployment as with
+	// DeletionPolicyOrphan, but first scales it down to zero replicas.
+	DeletionPolicyScaleToZero DeletionPolicy = "ScaleToZero"
+)
+
+// AdoptionPolicy describes whether the controller may adopt an existing
+// Deployment that is not controlled by the MyKind resource.
+// +kubebuilder:validation:Enum=Never;IfUnowned;Force
+type AdoptionPolicy string
+
+const (
+	// AdoptionPolicyNever never adopts an existing Deployment.
+	AdoptionPolicyNever AdoptionPolicy = "Never"
+
+	// AdoptionPolicyIfUnowned adopts an existing Deployment only if it is
+	// not controlled by another resource.
+	AdoptionPolicyIfUnowned AdoptionPolicy = "IfUnowned"
+
+	// AdoptionPolicyForce adopts an existing Deployment even if it is
+	// controlled by another resource, replacing that resource as its
+	// controller.
+	AdoptionPolicyForce AdoptionPolicy = "Force"
+)
+
+// DeploymentSpec describes the Deployment resource created for a MyKind
+// resource.
+type DeploymentSpec struct {
+	// Name is the name of the Deployment resource that the controller
+	// should create.
+	// This field must be specified.
+	// +kubebuilder:validation:MaxLength=64
+	Name string `json:"name"`
+
+	// Replicas is the number of replicas that should be specified on the
+	// Deployment resource that the controller creates.
+	// If not specified, it will be defaulted to one replica.
+	// It is ignored while spec.autoscaling is set, and if spec.workloadKind
+	// is DaemonSet.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Container describes the container that will be run in each pod of
+	// the Deployment resource that the controller creates.
+	// If not specified, a single nginx:latest container will be run.
+	// +optional
+	Container ContainerSpec `json:"container,omitempty"`
+
+	// ConfigMaps are ConfigMaps in the same namespace that are made
+	// available to the container. The pods of the Deployment are restarted
+	// whenever the contents of any of them change.
+	// +optional
+	ConfigMaps []ConfigReference `json:"configMaps,omitempty"`
+
+	// Secrets are Secrets in the same namespace that are made available to
+	// the container. The pods of the Deployment are restarted whenever any
+	// of them is modified or recreated. As the contents of Secrets are not
+	// hashed, this includes changes to only their labels or annotations.
+	// +optional
+	Secrets []ConfigReference `json:"secrets,omitempty"`
+}
+
+// ConfigReference refers to a ConfigMap or Secret that is made available to
+// the container of the Deployment created for a MyKind resource.
+type ConfigReference struct {
+	// Name is the name of the ConfigMap or Secret.
+	Name string `json:"name"`
+
+	// MountPath is the path within the container at which the ConfigMap or
+	// Secret is mounted as a volume.
+	// If not specified, each of its keys is exposed to the container as an
+	// environment variable instead.
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+// ContainerSpec describes the container that is run in each pod of the
+// Deployment created for a MyKind resource.
+type ContainerSpec struct {
+	// Name is the name of the container.
+	// If not specified, 'nginx' will be used.
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?$
+	Name string `json:"name,omitempty"`
+
+	// Image is the container image to run.
+	// If not specified, 'nginx:latest' will be used.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Command is the entrypoint array. The image's ENTRYPOINT is used if
+	// this is not provided.
+	// +optional
+	Command []string `json:"command,omitempty"`
+
+	// Args are the arguments to the entrypoint. The image's CMD is used if
+	// this is not provided.
+	// +optional
+	Args []string `json:"args,omitempty"`
+
+	// Env is a list of environment variables to set in the container.
+	// +optional
+	Env []core.EnvVar `json:"env,omitempty"`
+
+	// Ports is a list of ports to expose from the container.
+	// +optional
+	Ports []core.ContainerPort `json:"ports,omitempty"`
+
+	// Resources are the compute resources required by the container.
+	// +optional
+	Resources core.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// ServiceSpec describes the Service resource created for a MyKind
+// resource. The Service has the same name as the Deployment and selects
+// its pods.
+type ServiceSpec struct {
+	// Type is the type of the Service.
+	// If not specified, 'ClusterIP' will be used.
+	// +optional
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	Type core.ServiceType `json:"type,omitempty"`
+
+	// Ports is a list of ports exposed by the Service.
+	// If targetPort is not specified on a port, the value of port is used.
+	// +optional
+	Ports []core.ServicePort `json:"ports,omitempty"`
+
+	// Annotations are added to the Service.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Headless causes a headless Service to be created, which does not
+	// have a cluster IP. It may only be set if type is 'ClusterIP'.
+	// +optional
+	Headless bool `json:"headless,omitempty"`
+}
+
+// IngressSpec describes the Ingress resource created for a MyKind
+// resource. The Ingress has the same name as the Deployment and routes
+// traffic to the Service created for the MyKind resource.
+type IngressSpec struct {
+	// Hosts are the host names that are routed to the Service.
+	// If not specified, traffic for all hosts is routed to the Service.
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+
+	// Paths are the HTTP paths that are routed to the Service.
+	// If not specified, '/' will be used.
+	// +optional
+	Paths []string `json:"paths,omitempty"`
+
+	// ServicePort is the name or number of the port of the Service that
+	// traffic is routed to.
+	// If not specified, the first port of the Service will be used.
+	// +optional
+	ServicePort intstr.IntOrString `json:"servicePort,omitempty"`
+
+	// TLSSecretName is the name of a Secret containing the TLS certificate
+	// used to terminate TLS for the hosts.
+	// If not specified, TLS will not be configured.
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+
+	// IngressClass is the class of the ingress controller that should
+	// satisfy the Ingress, set using the 'kubernetes.io/ingress.class'
+	// annotation.
+	// +optional
+	IngressClass string `json:"ingressClass,omitempty"`
+
+	// Annotations are added to the Ingress.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// AutoscalingSpec describes the HorizontalPodAutoscaler created for a
+// MyKind resource.
+type AutoscalingSpec struct {
+	// MinReplicas is the lower limit for the number of replicas that the
+	// Deployment can be scaled down to.
+	// If not specified, it will be defaulted to one replica.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the upper limit for the number of replicas that the
+	// Deployment can be scaled up to.
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// TargetCPUUtilizationPercentage is the target average CPU utilization
+	// of the pods, as a percentage of their requested CPU.
+	// If neither this nor targetMemoryUtilizationPercentage is specified,
+	// it will be defaulted to 80%.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+
+	// TargetMemoryUtilizationPercentage is the target average memory
+	// utilization of the pods, as a percentage of their requested memory.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TargetMemoryUtilizationPercentage *int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+}
+
+// ScaleSchedule describes a period of time during which the workload of a
+// MyKind is scaled to a different number of replicas.
+type ScaleSchedule struct {
+	// Name identifies the schedule in the status of the MyKind.
+	Name string `json:"name"`
+
+	// Start is a cron expression, such as "0 20 * * 1-5", for the times at
+	// which the schedule becomes active.
+	Start string `json:"start"`
+
+	// End is a cron expression for the times at which the schedule stops
+	// being active. The schedule is active if it has started more
+	// recently than it has ended.
+	End string `json:"end"`
+
+	// Replicas is the number of replicas to scale the workload described by
+	// spec.deployment to while the schedule is active.
+	// +kubebuilder:validation:Minimum=0
+	Replicas int32 `json:"replicas"`
+
+	// Components are the numbers of replicas to scale the Deployments of
+	// components in spec.components to while the schedule is active.
+	// Components that are not listed keep their own replica counts.
+	// +optional
+	Components []ScheduledComponent `json:"components,omitempty"`
+
+	// TimeZone is the name of the time zone in the IANA time zone
+	// database, such as "Europe/London", in which start and end are
+	// evaluated.
+	// If not specified, they are evaluated in UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// ScheduledComponent is the number of replicas that a schedule scales the
+// Deployment of a component of a MyKind to.
+type ScheduledComponent struct {
+	// Name is the name of the component in spec.components.
+	Name string `json:"name"`
+
+	// Replicas is the number of replicas to scale the Deployment of the
+	// component to.
+	// +kubebuilder:validation:Minimum=0
+	Replicas int32 `json:"replicas"`
+}
+
+// DisruptionBudgetSpec describes the PodDisruptionBudget created for a
+// MyKind resource. Exactly one of minAvailable and maxUnavailable must be
+// specified.
+// The budget is not created while the Deployment has no replicas, and is
+// relaxed to allow one pod to be unavailable if it would otherwise never
+// allow any, so that it can never block a drain indefinitely.
+type DisruptionBudgetSpec struct {
+	// MinAvailable is the number or percentage of pods that must remain
+	// available during a voluntary disruption.
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+
+	// MaxUnavailable is the number or percentage of pods that may be
+	// unavailable during a voluntary disruption.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// MyKindStatus defines the observed state of MyKind
+type MyKindStatus struct {
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// ObservedGeneration is the most recent generation of the MyKind
+	// resource that has been observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// DeploymentName is the name of the workload resource currently
+	// managed for this MyKind resource.
+	// +optional
+	DeploymentName string `json:"deploymentName,omitempty"`
+
+	// WorkloadKind is the kind of the workload resource currently managed
+	// for this MyKind resource. The replica counts are those of the
+	// workload, or of the pods scheduled by it if it is a DaemonSet.
+	// +optional
+	WorkloadKind WorkloadKind `json:"workloadKind,omitempty"`
+
+	// Replicas is the total number of replicas observed on the Deployment
+	// resource created for this MyKind resource.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Selector is the label selector, in string form, that matches the pods
+	// of the Deployment resource created for this MyKind resource.
+	// It is used by the scale subresource.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// ReadyReplicas is the number of 'ready' replicas observed on the
+	// Deployment resource created for this MyKind resource.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// UpdatedReplicas is the number of replicas observed on the Deployment
+	// resource that are running the most recent pod template.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+
+	// AvailableReplicas is the number of 'available' replicas observed on
+	// the Deployment resource created for this MyKind resource.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+
+	// UnavailableReplicas is the number of replicas observed on the
+	// Deployment resource that are not yet available.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	UnavailableReplicas int32 `json:"unavailableReplicas,omitempty"`
+
+	// CurrentRevision is the name of the ControllerRevision recording the
+	// spec of this MyKind resource that was last reconciled.
+	// +optional
+	CurrentRevision string `json:"currentRevision,omitempty"`
+
+	// LastKnownGoodRevision is the name of the latest ControllerRevision
+	// whose spec was rolled out with the MyKind becoming Ready. It is kept
+	// however many revisions are made after it.
+	// +optional
+	LastKnownGoodRevision string `json:"lastKnownGoodRevision,omitempty"`
+
+	// ActiveSchedule is the name of the schedule in spec.schedules that the
+	// workload was last scaled by, if any.
+	// +optional
+	ActiveSchedule string `json:"activeSchedule,omitempty"`
+
+	// Components are the observed states of the Deployments created for
+	// the components in spec.components, in the same order.
+	// +optional
+	Components []ComponentStatus `json:"components,omitempty"`
+
+	// Canary is the state of the canary rollout of a new pod template to
+	// the workload, while one is in progress or has been aborted. The
+	// replica counts above are those of the stable Deployment.
+	// +optional
+	Canary *CanaryStatus `json:"canary,omitempty"`
+
+	// BlueGreen is the state of the blue/green rollout of the workload, if
+	// spec.rollout.blueGreen is set. The replica counts above are those of
+	// the active Deployment, or of the preview Deployment until there is an
+	// active one.
+	// +optional
+	BlueGreen *BlueGreenStatus `json:"blueGreen,omitempty"`
+
+	// LoadBalancer is the load-balancer status of the Ingress resource
+	// created for this MyKind resource, containing the addresses at which
+	// it can be reached.
+	// +optional
+	LoadBalancer core.LoadBalancerStatus `json:"loadBalancer,omitempty"`
+
+	// Conditions represent the latest available observations of the
+	// MyKind resource's state.
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []MyKindCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// ComponentStatus describes the observed state of the Deployment created
+// for a component of a MyKind resource.
+type ComponentStatus struct {
+	// Name is the name of the component.
+	Name string `json:"name"`
+
+	// DeploymentName is the name of the Deployment of the component, if it
+	// has been observed.
+	// +optional
+	DeploymentName string `json:"deploymentName,omitempty"`
+
+	// Replicas is the total number of replicas observed on the Deployment.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of 'ready' replicas observed on the
+	// Deployment.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// UpdatedReplicas is the number of replicas observed on the Deployment
+	// that are running the most recent pod template.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+
+	// AvailableReplicas is the number of 'available' replicas observed on
+	// the Deployment.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+
+	// Available is the status of the Available condition of the
+	// Deployment, or Unknown if it has not been observed.
+	Available core.ConditionStatus `json:"available"`
+}
+
+// CanaryStatus describes the state of a canary rollout.
+type CanaryStatus struct {
+	// DeploymentName is the name of the canary Deployment.
+	DeploymentName string `json:"deploymentName"`
+
+	// TemplateHash identifies the pod template that is being rolled out.
+	TemplateHash string `json:"templateHash"`
+
+	// Phase is the phase of the rollout.
+	Phase CanaryPhase `json:"phase"`
+
+	// CurrentStep is the index in spec.rollout.canary.steps of the step
+	// that the rollout is at. It is equal to the number of steps once they
+	// are all complete.
+	CurrentStep int32 `json:"currentStep"`
+
+	// StepReadyTime is the time at which the canary replicas of the current
+	// step were first observed to be available, from which its pause is
+	// measured.
+	// +optional
+	StepReadyTime *metav1.Time `json:"stepReadyTime,omitempty"`
+
+	// Replicas is the total number of replicas observed on the canary
+	// Deployment.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of 'ready' replicas observed on the
+	// canary Deployment.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+}
+
+// CanaryPhase is the phase of a canary rollout.
+type CanaryPhase string
+
+const (
+	// CanaryPhaseProgressing means that the rollout is waiting for the
+	// canary replicas of the current step to become available.
+	CanaryPhaseProgressing CanaryPhase = "Progressing"
+
+	// CanaryPhasePaused means that the rollout is waiting for the pause of
+	// the current step to elapse.
+	CanaryPhasePaused CanaryPhase = "Paused"
+
+	// CanaryPhaseAwaitingPromotion means that the rollout is waiting for
+	// the current step to be promoted with the PromoteAnnotation.
+	CanaryPhaseAwaitingPromotion CanaryPhase = "AwaitingPromotion"
+
+	// CanaryPhasePromoting means that all of the steps are complete and the
+	// stable Deployment is being updated to the new pod template. The
+	// canary Deployment is deleted once it has been rolled out.
+	CanaryPhasePromoting CanaryPhase = "Promoting"
+
+	// CanaryPhaseAborted means that the rollout was aborted with
+	// spec.rollout.canary.abort, and the stable Deployment is running all
+	// of the replicas with the previous pod template.
+	CanaryPhaseAborted CanaryPhase = "Aborted"
+)
+
+// BlueGreenStatus describes the state of a blue/green rollout.
+type BlueGreenStatus struct {
+	// ActiveColor is the colour of the Deployment that the Service routes
+	// traffic to. It is empty until the first Deployment has been rolled
+	// out, and until then the Service routes traffic to all of the pods of
+	// the MyKind.
+	// +optional
+	ActiveColor DeploymentColor `json:"activeColor,omitempty"`
+
+	// PreviewColor is the colour of the Deployment running a pod template
+	// that has not yet been promoted, if there is one.
+	// +optional
+	PreviewColor DeploymentColor `json:"previewColor,omitempty"`
+
+	// PreviewAvailable is true once all of the replicas of the preview
+	// Deployment are updated and available, from when it may be promoted.
+	// +optional
+	PreviewAvailable bool `json:"previewAvailable,omitempty"`
+
+	// ScaleDownTime is the time after which the previously active
+	// Deployment is deleted, if it is being kept after a promotion.
+	// +optional
+	ScaleDownTime *metav1.Time `json:"scaleDownTime,omitempty"`
+}
+
+// MyKindConditionType is the type of a condition on a MyKind resource.
+type MyKindConditionType string
+
+const (
+	// MyKindReady is True when the MyKind resource has been fully
+	// reconciled and its Deployment is available and not progressing.
+	MyKindReady MyKindConditionType = "Ready"
+
+	// MyKindProgressing is True while the Deployment for the MyKind
+	// resource is being created, scaled or rolled out.
+	MyKindProgressing MyKindConditionType = "Progressing"
+
+	// MyKindDegraded is True when the controller failed to reconcile the
+	// MyKind resource or its Deployment has failed to make progress.
+	MyKindDegraded MyKindConditionType = "Degraded"
+
+	// MyKindDeploymentAvailable mirrors the Available condition of the
+	// Deployment managed for the MyKind resource.
+	MyKindDeploymentAvailable MyKindConditionType = "DeploymentAvailable"
+
+	// MyKindDeploymentNameConflict is True when a Deployment named
+	// spec.deployment.name exists but cannot be adopted by the MyKind
+	// resource.
+	MyKindDeploymentNameConflict MyKindConditionType = "DeploymentNameConflict"
+
+	// MyKindPaused is True while reconciliation of the MyKind resource is
+	// paused by spec.paused or the PausedAnnotation.
+	MyKindPaused MyKindConditionType = "Paused"
+)
+
+// MyKindCondition describes the state of a MyKind resource at a certain
+// point.
+type MyKindCondition struct {
+	// Type of the condition.
+	Type MyKindConditionType `json:"type"`
+
+	// Status of the condition, one of True, False or Unknown.
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status core.ConditionStatus `json:"status"`
+
+	// ObservedGeneration is the generation of the MyKind resource that the
+	// condition was set based upon.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastTransitionTime is the last time the condition transitioned from
+	// one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Reason is a brief CamelCase reason for the condition's last
+	// transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable message indicating details about the
+	// last transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.deployment.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:storageversion
+
+// MyKind is the Schema for the mykinds API
+type MyKind struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MyKindSpec   `json:"spec,omitempty"`
+	Status MyKindStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MyKindList contains a list of MyKind
+type MyKindList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MyKind `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MyKind{}, &MyKindList{})
+}
@@ -0,0 +1,860 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"jetstack.io/example-controller/pkg/cron"
+)
+
+// log is for logging in this package.
+var mykindlog = logf.Log.WithName("mykind-resource")
+
+// webhookClient is used by the webhooks to look up other MyKind resources
+// and the workloads created for them.
+// It reads from the apiserver rather than the manager's cache, which may
+// not yet have observed a resource that was just created or renamed.
+// It is set by SetupWebhookWithManager, and checks that require it are
+// skipped if it is nil.
+var webhookClient client.Reader
+
+// SetupWebhookWithManager registers the webhooks for MyKind with the
+// manager's webhook server.
+func (r *MyKind) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	webhookClient = mgr.GetAPIReader()
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-mygroup-k8s-io-v1-mykind,mutating=true,failurePolicy=fail,groups=mygroup.k8s.io,resources=mykinds,verbs=create;update,versions=v1,name=mmykind.kb.io
+
+var _ webhook.Defaulter = &MyKind{}
+
+const (
+	// DefaultReplicas is the number of replicas used if
+	// spec.deployment.replicas is not specified.
+	DefaultReplicas = 1
+
+	// DefaultContainerName is the container name used if
+	// spec.deployment.container.name is not specified.
+	DefaultContainerName = "nginx"
+
+	// DefaultContainerImage is the container image used if
+	// spec.deployment.container.image is not specified.
+	DefaultContainerImage = "nginx:latest"
+
+	// DefaultDeletionPolicy is the deletion policy used if
+	// spec.deletionPolicy is not specified.
+	DefaultDeletionPolicy = DeletionPolicyDelete
+
+	// DefaultAdoptionPolicy is the adoption policy used if
+	// spec.adoptionPolicy is not specified.
+	DefaultAdoptionPolicy = AdoptionPolicyNever
+
+	// DefaultWorkloadKind is the kind of workload created if
+	// spec.workloadKind is not specified.
+	DefaultWorkloadKind = WorkloadKindDeployment
+
+	// DefaultTargetCPUUtilizationPercentage is the target CPU utilization
+	// used if spec.autoscaling does not specify any target.
+	DefaultTargetCPUUtilizationPercentage = 80
+
+	// DefaultRevisionHistoryLimit is the number of previous revisions kept
+	// if spec.revisionHistoryLimit is not specified.
+	DefaultRevisionHistoryLimit = 10
+
+	// DefaultScaleDownDelay is the delay used if
+	// spec.rollout.blueGreen.scaleDownDelay is not specified.
+	DefaultScaleDownDelay = 30 * time.Second
+)
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type.
+// It is also called by the controller, so that resources persisted while
+// the webhook was unavailable are handled the same way.
+func (r *MyKind) Default() {
+	if r.Labels == nil {
+		r.Labels = map[string]string{}
+	}
+	r.Labels[DeploymentNameLabel] = r.Spec.Deployment.Name
+
+	r.Spec.Deployment.Default()
+	for i := range r.Spec.Components {
+		r.Spec.Components[i].Deployment.Default()
+	}
+
+	if r.Spec.Service != nil {
+		r.Spec.Service.Default()
+	}
+
+	if r.Spec.Ingress != nil {
+		r.Spec.Ingress.Default(r.Spec.Service)
+	}
+
+	if r.Spec.Autoscaling != nil {
+		r.Spec.Autoscaling.Default()
+	}
+
+	if r.Spec.Rollout != nil && r.Spec.Rollout.BlueGreen != nil {
+		r.Spec.Rollout.BlueGreen.Default()
+	}
+
+	if r.Spec.DeletionPolicy == "" {
+		r.Spec.DeletionPolicy = DefaultDeletionPolicy
+	}
+
+	if r.Spec.AdoptionPolicy == "" {
+		r.Spec.AdoptionPolicy = DefaultAdoptionPolicy
+	}
+
+	if r.Spec.WorkloadKind == "" {
+		r.Spec.WorkloadKind = DefaultWorkloadKind
+	}
+
+	if r.Spec.RevisionHistoryLimit == nil {
+		r.Spec.RevisionHistoryLimit = pointer.Int32Ptr(DefaultRevisionHistoryLimit)
+	}
+}
+
+// Default sets the default values of any unset fields of the Deployment.
+func (d *DeploymentSpec) Default() {
+	if d.Replicas == nil {
+		d.Replicas = pointer.Int32Ptr(DefaultReplicas)
+	}
+
+	d.Container.Default()
+}
+
+// Default sets the default values of any unset fields of the container.
+// Fields that the apiserver would otherwise default on the Deployment's pod
+// template are set too, so that the rendered container can be compared
+// against the existing Deployment.
+func (c *ContainerSpec) Default() {
+	if c.Name == "" {
+		c.Name = DefaultContainerName
+	}
+	if c.Image == "" {
+		c.Image = DefaultContainerImage
+	}
+	for i := range c.Ports {
+		if c.Ports[i].Protocol == "" {
+			c.Ports[i].Protocol = core.ProtocolTCP
+		}
+	}
+	for i := range c.Env {
+		if ref := c.Env[i].ValueFrom; ref != nil && ref.FieldRef != nil && ref.FieldRef.APIVersion == "" {
+			ref.FieldRef.APIVersion = "v1"
+		}
+	}
+}
+
+// Default sets the default values of any unset fields of the Service.
+// Fields that the apiserver would otherwise default on the Service are set
+// too, so that the rendered Service can be compared against the existing
+// one.
+func (s *ServiceSpec) Default() {
+	if s.Type == "" {
+		s.Type = core.ServiceTypeClusterIP
+	}
+	for i := range s.Ports {
+		if s.Ports[i].Protocol == "" {
+			s.Ports[i].Protocol = core.ProtocolTCP
+		}
+		if s.Ports[i].TargetPort == (intstr.IntOrString{}) {
+			s.Ports[i].TargetPort = intstr.FromInt(int(s.Ports[i].Port))
+		}
+	}
+}
+
+// Default sets the default values of any unset fields of the autoscaling
+// spec.
+func (a *AutoscalingSpec) Default() {
+	if a.MinReplicas == nil {
+		a.MinReplicas = pointer.Int32Ptr(1)
+	}
+	if a.TargetCPUUtilizationPercentage == nil && a.TargetMemoryUtilizationPercentage == nil {
+		a.TargetCPUUtilizationPercentage = pointer.Int32Ptr(DefaultTargetCPUUtilizationPercentage)
+	}
+}
+
+// Default sets the default values of any unset fields of the blue/green
+// rollout strategy.
+func (b *BlueGreenStrategy) Default() {
+	if b.ScaleDownDelay == nil {
+		b.ScaleDownDelay = &metav1.Duration{Duration: DefaultScaleDownDelay}
+	}
+}
+
+// DefaultIngressPath is the path routed by the Ingress if
+// spec.ingress.paths is not specified.
+const DefaultIngressPath = "/"
+
+// Default sets the default values of any unset fields of the Ingress.
+// service is the Service spec of the same MyKind, which may be nil.
+func (i *IngressSpec) Default(service *ServiceSpec) {
+	if len(i.Paths) == 0 {
+		i.Paths = []string{DefaultIngressPath}
+	}
+	if i.ServicePort == (intstr.IntOrString{}) && service != nil && len(service.Ports) > 0 {
+		if name := service.Ports[0].Name; name != "" {
+			i.ServicePort = intstr.FromString(name)
+		} else {
+			i.ServicePort = intstr.FromInt(int(service.Ports[0].Port))
+		}
+	}
+}
+
+// +kubebuilder:webhook:path=/validate-mygroup-k8s-io-v1-mykind,mutating=false,failurePolicy=fail,groups=mygroup.k8s.io,resources=mykinds,verbs=create;update,versions=v1,name=vmykind.kb.io
+
+var _ webhook.Validator = &MyKind{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *MyKind) ValidateCreate() error {
+	mykindlog.Info("validate create", "name", r.Name)
+
+	allErrs := r.validateSpec()
+	allErrs = append(allErrs, r.validateDeploymentNameUnique()...)
+
+	return r.toAggregateError(allErrs)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *MyKind) ValidateUpdate(old runtime.Object) error {
+	mykindlog.Info("validate update", "name", r.Name)
+
+	oldMyKind := old.(*MyKind)
+
+	allErrs := r.validateSpec()
+	allErrs = append(allErrs, r.validateImmutableFields(oldMyKind)...)
+	if !sets.NewString(r.deploymentNames()...).Equal(sets.NewString(oldMyKind.deploymentNames()...)) {
+		allErrs = append(allErrs, r.validateDeploymentNameUnique()...)
+	}
+
+	return r.toAggregateError(allErrs)
+}
+
+// validateSpec checks the fields of the MyKind's spec that cannot be
+// expressed with OpenAPI validation in the CRD.
+func (r *MyKind) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+
+	allErrs = append(allErrs, r.Spec.Deployment.validate(field.NewPath("spec", "deployment"))...)
+
+	componentNames := map[string]bool{}
+	deploymentNames := map[string]bool{r.Spec.Deployment.Name: true}
+	for _, ref := range r.rolloutDeploymentNameRefs() {
+		deploymentNames[ref.name] = true
+	}
+	for i, component := range r.Spec.Components {
+		componentPath := field.NewPath("spec", "components").Index(i)
+		for _, msg := range validation.IsDNS1123Label(component.Name) {
+			allErrs = append(allErrs, field.Invalid(componentPath.Child("name"), component.Name, msg))
+		}
+		if componentNames[component.Name] {
+			allErrs = append(allErrs, field.Duplicate(componentPath.Child("name"), component.Name))
+		}
+		componentNames[component.Name] = true
+
+		allErrs = append(allErrs, component.Deployment.validate(componentPath.Child("deployment"))...)
+		if deploymentNames[component.Deployment.Name] {
+			allErrs = append(allErrs, field.Duplicate(componentPath.Child("deployment", "name"), component.Deployment.Name))
+		}
+		deploymentNames[component.Deployment.Name] = true
+	}
+
+	if r.Spec.Service != nil {
+		allErrs = append(allErrs, r.Spec.Service.validate(field.NewPath("spec", "service"))...)
+		// The Service is named after the Deployment, and Service names
+		// are more restricted than Deployment names.
+		for _, msg := range validation.IsDNS1035Label(r.Spec.Deployment.Name) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "deployment", "name"), r.Spec.Deployment.Name, "must be a valid Service name when spec.service is set: "+msg))
+		}
+	}
+
+	if a := r.Spec.Autoscaling; a != nil && a.MinReplicas != nil && *a.MinReplicas > a.MaxReplicas {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "autoscaling", "maxReplicas"), a.MaxReplicas, "must be greater than or equal to minReplicas"))
+	}
+
+	if r.Spec.DisruptionBudget != nil {
+		allErrs = append(allErrs, r.Spec.DisruptionBudget.validate(field.NewPath("spec", "disruptionBudget"))...)
+	}
+
+	if r.Spec.WorkloadKind == WorkloadKindStatefulSet {
+		// The Service is the governing service of the StatefulSet, which
+		// gives each of its pods a stable network identity.
+		if r.Spec.Service == nil {
+			allErrs = append(allErrs, field.Required(field.NewPath("spec", "service"), "must be specified when spec.workloadKind is StatefulSet"))
+		} else if !r.Spec.Service.Headless {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "service", "headless"), r.Spec.Service.Headless, "must be true when spec.workloadKind is StatefulSet"))
+		}
+	}
+
+	if r.Spec.WorkloadKind == WorkloadKindDaemonSet {
+		if r.Spec.Autoscaling != nil {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "autoscaling"), "may not be specified when spec.workloadKind is DaemonSet"))
+		}
+		if r.Spec.DeletionPolicy == DeletionPolicyScaleToZero {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "deletionPolicy"), "may not be ScaleToZero when spec.workloadKind is DaemonSet"))
+		}
+	}
+
+	if r.canaryEnabled() {
+		allErrs = append(allErrs, r.validateCanary(field.NewPath("spec", "rollout", "canary"))...)
+	}
+
+	if r.blueGreenEnabled() {
+		allErrs = append(allErrs, r.validateBlueGreen(field.NewPath("spec", "rollout", "blueGreen"))...)
+	}
+
+	if len(r.Spec.Schedules) > 0 {
+		allErrs = append(allErrs, r.validateSchedules(field.NewPath("spec", "schedules"))...)
+	}
+
+	if r.Spec.Ingress != nil {
+		if r.Spec.Service == nil {
+			allErrs = append(allErrs, field.Required(field.NewPath("spec", "service"), "must be specified when spec.ingress is set"))
+		}
+		allErrs = append(allErrs, r.Spec.Ingress.validate(field.NewPath("spec", "ingress"), r.Spec.Service)...)
+	}
+
+	return allErrs
+}
+
+// validateCanary checks the canary rollout strategy of the MyKind.
+func (r *MyKind) validateCanary(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if r.Spec.WorkloadKind != "" && r.Spec.WorkloadKind != WorkloadKindDeployment {
+		allErrs = append(allErrs, field.Forbidden(path, "may only be specified when spec.workloadKind is Deployment"))
+	}
+	if r.Spec.Autoscaling != nil {
+		allErrs = append(allErrs, field.Forbidden(path, "may not be specified when spec.autoscaling is set"))
+	}
+
+	canary := r.Spec.Rollout.Canary
+	if len(canary.Steps) == 0 {
+		allErrs = append(allErrs, field.Required(path.Child("steps"), "at least one step must be specified"))
+	}
+	for i, step := range canary.Steps {
+		stepPath := path.Child("steps").Index(i)
+		switch {
+		case step.Weight == nil && step.Replicas == nil:
+			allErrs = append(allErrs, field.Required(stepPath, "one of weight or replicas must be specified"))
+		case step.Weight != nil && step.Replicas != nil:
+			allErrs = append(allErrs, field.Forbidden(stepPath.Child("replicas"), "may not be specified when weight is specified"))
+		}
+		if step.Pause != nil && step.Pause.Duration < 0 {
+			allErrs = append(allErrs, field.Invalid(stepPath.Child("pause"), step.Pause.Duration.String(), "must be greater than or equal to 0"))
+		}
+	}
+
+	return allErrs
+}
+
+// canaryEnabled returns true if the MyKind is rolled out with a canary
+// Deployment.
+func (r *MyKind) canaryEnabled() bool {
+	return r.Spec.Rollout != nil && r.Spec.Rollout.Canary != nil
+}
+
+// validateBlueGreen checks the blue/green rollout strategy of the MyKind.
+func (r *MyKind) validateBlueGreen(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if r.Spec.WorkloadKind != "" && r.Spec.WorkloadKind != WorkloadKindDeployment {
+		allErrs = append(allErrs, field.Forbidden(path, "may only be specified when spec.workloadKind is Deployment"))
+	}
+	if r.Spec.Autoscaling != nil {
+		allErrs = append(allErrs, field.Forbidden(path, "may not be specified when spec.autoscaling is set"))
+	}
+	if r.canaryEnabled() {
+		allErrs = append(allErrs, field.Forbidden(path, "may not be specified when spec.rollout.canary is set"))
+	}
+	if r.Spec.Service == nil {
+		allErrs = append(allErrs, field.Required(field.NewPath("spec", "service"), "must be specified when spec.rollout.blueGreen is set"))
+	}
+	if preview := r.Spec.Deployment.Name + PreviewServiceSuffix; len(preview) > validation.DNS1035LabelMaxLength {
+		allErrs = append(allErrs, field.TooLong(field.NewPath("spec", "deployment", "name"), r.Spec.Deployment.Name, validation.DNS1035LabelMaxLength-len(PreviewServiceSuffix)))
+	}
+
+	if d := r.Spec.Rollout.BlueGreen.ScaleDownDelay; d != nil && d.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("scaleDownDelay"), d.Duration.String(), "must be greater than or equal to 0"))
+	}
+
+	return allErrs
+}
+
+// validateSchedules checks the scaling schedules of the MyKind.
+func (r *MyKind) validateSchedules(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if r.Spec.WorkloadKind == WorkloadKindDaemonSet {
+		allErrs = append(allErrs, field.Forbidden(path, "may not be specified when spec.workloadKind is DaemonSet"))
+	}
+	if r.Spec.Autoscaling != nil {
+		allErrs = append(allErrs, field.Forbidden(path, "may not be specified when spec.autoscaling is set"))
+	}
+
+	componentNames := map[string]bool{}
+	for _, component := range r.Spec.Components {
+		componentNames[component.Name] = true
+	}
+
+	names := map[string]bool{}
+	for i, schedule := range r.Spec.Schedules {
+		schedulePath := path.Index(i)
+		for _, msg := range validation.IsDNS1123Label(schedule.Name) {
+			allErrs = append(allErrs, field.Invalid(schedulePath.Child("name"), schedule.Name, msg))
+		}
+		if names[schedule.Name] {
+			allErrs = append(allErrs, field.Duplicate(schedulePath.Child("name"), schedule.Name))
+		}
+		names[schedule.Name] = true
+
+		if _, err := cron.Parse(schedule.Start); err != nil {
+			allErrs = append(allErrs, field.Invalid(schedulePath.Child("start"), schedule.Start, err.Error()))
+		}
+		if _, err := cron.Parse(schedule.End); err != nil {
+			allErrs = append(allErrs, field.Invalid(schedulePath.Child("end"), schedule.End, err.Error()))
+		}
+		if schedule.Replicas < 0 {
+			allErrs = append(allErrs, field.Invalid(schedulePath.Child("replicas"), schedule.Replicas, "must be greater than or equal to 0"))
+		}
+		if schedule.TimeZone != "" {
+			if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+				allErrs = append(allErrs, field.Invalid(schedulePath.Child("timeZone"), schedule.TimeZone, "must be a time zone in the IANA time zone database"))
+			}
+		}
+
+		scheduledComponents := map[string]bool{}
+		for j, component := range schedule.Components {
+			componentPath := schedulePath.Child("components").Index(j)
+			if !componentNames[component.Name] {
+				allErrs = append(allErrs, field.NotFound(componentPath.Child("name"), component.Name))
+			}
+			if scheduledComponents[component.Name] {
+				allErrs = append(allErrs, field.Duplicate(componentPath.Child("name"), component.Name))
+			}
+			scheduledComponents[component.Name] = true
+			if component.Replicas < 0 {
+				allErrs = append(allErrs, field.Invalid(componentPath.Child("replicas"), component.Replicas, "must be greater than or equal to 0"))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+// blueGreenEnabled returns true if the MyKind is rolled out with blue and
+// green Deployments.
+func (r *MyKind) blueGreenEnabled() bool {
+	return r.Spec.Rollout != nil && r.Spec.Rollout.BlueGreen != nil
+}
+
+// rolloutDeploymentNameRefs returns the names of the Deployments created by
+// the rollout strategy of the MyKind, in addition to spec.deployment.name.
+func (r *MyKind) rolloutDeploymentNameRefs() []deploymentNameRef {
+	var refs []deploymentNameRef
+	if r.canaryEnabled() {
+		refs = append(refs, deploymentNameRef{name: r.Spec.Deployment.Name + CanaryDeploymentSuffix, path: field.NewPath("spec", "rollout", "canary")})
+	}
+	if r.blueGreenEnabled() {
+		for _, color := range []DeploymentColor{DeploymentColorBlue, DeploymentColorGreen} {
+			refs = append(refs, deploymentNameRef{name: r.Spec.Deployment.Name + "-" + string(color), path: field.NewPath("spec", "rollout", "blueGreen")})
+		}
+	}
+	return refs
+}
+
+// validate checks the fields of the Deployment spec that cannot be
+// expressed with OpenAPI validation in the CRD.
+func (d *DeploymentSpec) validate(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for _, msg := range validation.IsDNS1123Subdomain(d.Name) {
+		allErrs = append(allErrs, field.Invalid(path.Child("name"), d.Name, msg))
+	}
+
+	container := d.Container
+	containerPath := path.Child("container")
+	if name := container.Name; name != "" {
+		for _, msg := range validation.IsDNS1123Label(name) {
+			allErrs = append(allErrs, field.Invalid(containerPath.Child("name"), name, msg))
+		}
+	}
+
+	envNames := map[string]bool{}
+	for i, env := range container.Env {
+		envPath := containerPath.Child("env").Index(i)
+		for _, msg := range validation.IsEnvVarName(env.Name) {
+			allErrs = append(allErrs, field.Invalid(envPath.Child("name"), env.Name, msg))
+		}
+		if envNames[env.Name] {
+			allErrs = append(allErrs, field.Duplicate(envPath.Child("name"), env.Name))
+		}
+		envNames[env.Name] = true
+		if env.Value != "" && env.ValueFrom != nil {
+			allErrs = append(allErrs, field.Invalid(envPath.Child("valueFrom"), "", "may not be specified when `value` is not empty"))
+		}
+	}
+
+	portNames := map[string]bool{}
+	for i, port := range container.Ports {
+		portPath := containerPath.Child("ports").Index(i)
+		for _, msg := range validation.IsValidPortNum(int(port.ContainerPort)) {
+			allErrs = append(allErrs, field.Invalid(portPath.Child("containerPort"), port.ContainerPort, msg))
+		}
+		if port.Name == "" {
+			continue
+		}
+		for _, msg := range validation.IsValidPortName(port.Name) {
+			allErrs = append(allErrs, field.Invalid(portPath.Child("name"), port.Name, msg))
+		}
+		if portNames[port.Name] {
+			allErrs = append(allErrs, field.Duplicate(portPath.Child("name"), port.Name))
+		}
+		portNames[port.Name] = true
+	}
+
+	mountPaths := map[string]bool{}
+	allErrs = append(allErrs, validateConfigReferences(path.Child("configMaps"), d.ConfigMaps, mountPaths)...)
+	allErrs = append(allErrs, validateConfigReferences(path.Child("secrets"), d.Secrets, mountPaths)...)
+
+	return allErrs
+}
+
+// validateConfigReferences checks the given ConfigMap or Secret references.
+// mountPaths holds the mount paths already in use by the container, and is
+// updated with those of refs.
+func validateConfigReferences(path *field.Path, refs []ConfigReference, mountPaths map[string]bool) field.ErrorList {
+	var allErrs field.ErrorList
+
+	names := map[string]bool{}
+	for i, ref := range refs {
+		refPath := path.Index(i)
+		for _, msg := range validation.IsDNS1123Subdomain(ref.Name) {
+			allErrs = append(allErrs, field.Invalid(refPath.Child("name"), ref.Name, msg))
+		}
+		if names[ref.Name] {
+			allErrs = append(allErrs, field.Duplicate(refPath.Child("name"), ref.Name))
+		}
+		names[ref.Name] = true
+
+		if ref.MountPath == "" {
+			continue
+		}
+		if !strings.HasPrefix(ref.MountPath, "/") {
+			allErrs = append(allErrs, field.Invalid(refPath.Child("mountPath"), ref.MountPath, "must be an absolute path"))
+		}
+		if mountPaths[ref.MountPath] {
+			allErrs = append(allErrs, field.Duplicate(refPath.Child("mountPath"), ref.MountPath))
+		}
+		mountPaths[ref.MountPath] = true
+	}
+
+	return allErrs
+}
+
+// validate checks the fields of the Service spec that cannot be expressed
+// with OpenAPI validation in the CRD.
+func (s *ServiceSpec) validate(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if s.Headless && s.Type != "" && s.Type != core.ServiceTypeClusterIP {
+		allErrs = append(allErrs, field.Invalid(path.Child("headless"), s.Headless, "may only be set if type is 'ClusterIP'"))
+	}
+	if len(s.Ports) == 0 && !s.Headless {
+		allErrs = append(allErrs, field.Required(path.Child("ports"), "must be specified unless headless is set"))
+	}
+
+	portNames := map[string]bool{}
+	for i, port := range s.Ports {
+		portPath := path.Child("ports").Index(i)
+		for _, msg := range validation.IsValidPortNum(int(port.Port)) {
+			allErrs = append(allErrs, field.Invalid(portPath.Child("port"), port.Port, msg))
+		}
+		if port.NodePort != 0 && s.Type != core.ServiceTypeNodePort && s.Type != core.ServiceTypeLoadBalancer {
+			allErrs = append(allErrs, field.Forbidden(portPath.Child("nodePort"), "may only be set if type is 'NodePort' or 'LoadBalancer'"))
+		}
+		if port.Name == "" {
+			if len(s.Ports) > 1 {
+				allErrs = append(allErrs, field.Required(portPath.Child("name"), "must be specified when there is more than one port"))
+			}
+			continue
+		}
+		for _, msg := range validation.IsDNS1123Label(port.Name) {
+			allErrs = append(allErrs, field.Invalid(portPath.Child("name"), port.Name, msg))
+		}
+		if portNames[port.Name] {
+			allErrs = append(allErrs, field.Duplicate(portPath.Child("name"), port.Name))
+		}
+		portNames[port.Name] = true
+	}
+
+	return allErrs
+}
+
+// validate checks the fields of the Ingress spec that cannot be expressed
+// with OpenAPI validation in the CRD.
+// service is the Service spec of the same MyKind, which may be nil.
+func (i *IngressSpec) validate(path *field.Path, service *ServiceSpec) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for j, host := range i.Hosts {
+		var msgs []string
+		if strings.HasPrefix(host, "*.") {
+			msgs = validation.IsWildcardDNS1123Subdomain(host)
+		} else {
+			msgs = validation.IsDNS1123Subdomain(host)
+		}
+		for _, msg := range msgs {
+			allErrs = append(allErrs, field.Invalid(path.Child("hosts").Index(j), host, msg))
+		}
+	}
+
+	for j, p := range i.Paths {
+		if !strings.HasPrefix(p, "/") {
+			allErrs = append(allErrs, field.Invalid(path.Child("paths").Index(j), p, "must be an absolute path"))
+		}
+	}
+
+	if i.TLSSecretName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(i.TLSSecretName) {
+			allErrs = append(allErrs, field.Invalid(path.Child("tlsSecretName"), i.TLSSecretName, msg))
+		}
+	}
+
+	if service != nil && i.ServicePort != (intstr.IntOrString{}) && !servicePortExists(service, i.ServicePort) {
+		allErrs = append(allErrs, field.Invalid(path.Child("servicePort"), i.ServicePort.String(), "must match the name or number of a port in spec.service.ports"))
+	}
+
+	return allErrs
+}
+
+// servicePortExists returns true if port refers to one of the ports of the
+// Service by name or number.
+func servicePortExists(service *ServiceSpec, port intstr.IntOrString) bool {
+	for _, p := range service.Ports {
+		if port.Type == intstr.String && p.Name == port.StrVal {
+			return true
+		}
+		if port.Type == intstr.Int && p.Port == port.IntVal {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the fields of the disruption budget spec that cannot be
+// expressed with OpenAPI validation in the CRD.
+func (d *DisruptionBudgetSpec) validate(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch {
+	case d.MinAvailable == nil && d.MaxUnavailable == nil:
+		allErrs = append(allErrs, field.Required(path, "one of minAvailable or maxUnavailable must be specified"))
+	case d.MinAvailable != nil && d.MaxUnavailable != nil:
+		allErrs = append(allErrs, field.Forbidden(path.Child("maxUnavailable"), "may not be specified when minAvailable is specified"))
+	}
+
+	allErrs = append(allErrs, validateIntOrPercent(path.Child("minAvailable"), d.MinAvailable)...)
+	allErrs = append(allErrs, validateIntOrPercent(path.Child("maxUnavailable"), d.MaxUnavailable)...)
+
+	return allErrs
+}
+
+// validateIntOrPercent checks that value, if set, is either a non-negative
+// integer or a percentage between 0% and 100%.
+func validateIntOrPercent(path *field.Path, value *intstr.IntOrString) field.ErrorList {
+	if value == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	switch value.Type {
+	case intstr.Int:
+		if value.IntVal < 0 {
+			allErrs = append(allErrs, field.Invalid(path, value.IntVal, "must be greater than or equal to 0"))
+		}
+	case intstr.String:
+		for _, msg := range validation.IsValidPercent(value.StrVal) {
+			allErrs = append(allErrs, field.Invalid(path, value.StrVal, msg))
+		}
+		if v, err := intstr.GetValueFromIntOrPercent(value, 100, false); err == nil && v > 100 {
+			allErrs = append(allErrs, field.Invalid(path, value.StrVal, "must not be greater than 100%"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateImmutableFields checks that fields which may not be changed after
+// creation, or in the current state of the MyKind, have not been changed.
+func (r *MyKind) validateImmutableFields(old *MyKind) field.ErrorList {
+	var allErrs field.ErrorList
+
+	// Once a MyKind is being deleted the controller may be acting on the
+	// Deployment named in its spec, so the spec may no longer be changed.
+	if old.DeletionTimestamp != nil && !apiequality.Semantic.DeepEqual(r.Spec, old.Spec) {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec"), "may not be changed while the resource is being deleted"))
+	}
+
+	if r.Spec.Deployment.Name != old.Spec.Deployment.Name {
+		allErrs = append(allErrs, r.validatePreviousWorkloadsRemoved(old)...)
+	}
+
+	return allErrs
+}
+
+// validatePreviousWorkloadsRemoved checks that no workload is still
+// controlled by the MyKind other than those named in the old spec.
+// The controller removes the workload of a previous spec.deployment.name
+// after a rename, and a name that is still in use by such a workload is
+// not checked by validateDeploymentNameUnique, so the name may only be
+// changed again once that has happened.
+func (r *MyKind) validatePreviousWorkloadsRemoved(old *MyKind) field.ErrorList {
+	if webhookClient == nil {
+		return nil
+	}
+
+	path := field.NewPath("spec", "deployment", "name")
+	current := sets.NewString(old.deploymentNames()...)
+	lists := []struct {
+		kind WorkloadKind
+		list runtime.Object
+	}{
+		{WorkloadKindDeployment, &apps.DeploymentList{}},
+		{WorkloadKindStatefulSet, &apps.StatefulSetList{}},
+		{WorkloadKindDaemonSet, &apps.DaemonSetList{}},
+	}
+
+	for _, l := range lists {
+		list := l.list
+		if err := webhookClient.List(context.Background(), list, client.InNamespace(r.Namespace)); err != nil {
+			return field.ErrorList{field.InternalError(path, err)}
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return field.ErrorList{field.InternalError(path, err)}
+		}
+		for _, item := range items {
+			workload, err := meta.Accessor(item)
+			if err != nil {
+				return field.ErrorList{field.InternalError(path, err)}
+			}
+			if current.Has(workload.GetName()) {
+				continue
+			}
+			if ref := metav1.GetControllerOf(workload); ref != nil && ref.UID == old.UID {
+				return field.ErrorList{field.Forbidden(path,
+					fmt.Sprintf("may not be changed until the %s %q created for a previous name has been removed", l.kind, workload.GetName()))}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateDeploymentNameUnique checks that no other MyKind in the same
+// namespace manages a workload with the same name as the workload of this
+// MyKind or of any of its components.
+func (r *MyKind) validateDeploymentNameUnique() field.ErrorList {
+	if webhookClient == nil {
+		return nil
+	}
+
+	var myKinds MyKindList
+	if err := webhookClient.List(context.Background(), &myKinds, client.InNamespace(r.Namespace)); err != nil {
+		return field.ErrorList{field.InternalError(field.NewPath("spec", "deployment", "name"), err)}
+	}
+
+	taken := sets.NewString()
+	for _, other := range myKinds.Items {
+		if other.Name == r.Name {
+			continue
+		}
+		taken.Insert(other.deploymentNames()...)
+	}
+
+	var allErrs field.ErrorList
+	for _, ref := range r.deploymentNameRefs() {
+		if taken.Has(ref.name) {
+			allErrs = append(allErrs, field.Duplicate(ref.path, ref.name))
+		}
+	}
+
+	return allErrs
+}
+
+// deploymentNameRef is the name of a workload managed for a MyKind, along
+// with the path of the field that it is derived from.
+type deploymentNameRef struct {
+	name string
+	path *field.Path
+}
+
+// deploymentNameRefs returns the names of the workloads managed for the
+// MyKind and its components, including those created by its rollout
+// strategy.
+func (r *MyKind) deploymentNameRefs() []deploymentNameRef {
+	refs := []deploymentNameRef{{name: r.Spec.Deployment.Name, path: field.NewPath("spec", "deployment", "name")}}
+	refs = append(refs, r.rolloutDeploymentNameRefs()...)
+	for i, component := range r.Spec.Components {
+		refs = append(refs, deploymentNameRef{name: component.Deployment.Name, path: field.NewPath("spec", "components").Index(i).Child("deployment", "name")})
+	}
+	return refs
+}
+
+// deploymentNames returns the names of the workloads managed for the MyKind
+// and its components, including those created by its rollout strategy.
+func (r *MyKind) deploymentNames() []string {
+	var names []string
+	for _, ref := range r.deploymentNameRefs() {
+		names = append(names, ref.name)
+	}
+	return names
+}
+
+func (r *MyKind) toAggregateError(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("MyKind").GroupKind(), r.Name, allErrs)
+}
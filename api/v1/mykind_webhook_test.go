@@ -0,0 +1,736 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestMyKind(name, deploymentName string) *MyKind {
+	return &MyKind{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: MyKindSpec{
+			Deployment: DeploymentSpec{
+				Name: deploymentName,
+			},
+		},
+	}
+}
+
+func TestDefault(t *testing.T) {
+	myKind := newTestMyKind("test", "deployment-name")
+	myKind.Spec.Deployment.Container.Ports = []core.ContainerPort{{ContainerPort: 8080}}
+	myKind.Spec.Components = []ComponentSpec{{Name: "worker", Deployment: DeploymentSpec{Name: "worker"}}}
+	myKind.Default()
+
+	if myKind.Spec.Deployment.Replicas == nil || *myKind.Spec.Deployment.Replicas != DefaultReplicas {
+		t.Errorf("expected replicas to be defaulted to %d, got %v", DefaultReplicas, myKind.Spec.Deployment.Replicas)
+	}
+	if myKind.Spec.Deployment.Container.Name != DefaultContainerName {
+		t.Errorf("expected container name to be defaulted to %q, got %q", DefaultContainerName, myKind.Spec.Deployment.Container.Name)
+	}
+	if myKind.Spec.Deployment.Container.Image != DefaultContainerImage {
+		t.Errorf("expected container image to be defaulted to %q, got %q", DefaultContainerImage, myKind.Spec.Deployment.Container.Image)
+	}
+	if p := myKind.Spec.Deployment.Container.Ports[0].Protocol; p != core.ProtocolTCP {
+		t.Errorf("expected port protocol to be defaulted to %q, got %q", core.ProtocolTCP, p)
+	}
+	if r := myKind.Spec.Components[0].Deployment.Replicas; r == nil || *r != DefaultReplicas {
+		t.Errorf("expected component replicas to be defaulted to %d, got %v", DefaultReplicas, r)
+	}
+	if i := myKind.Spec.Components[0].Deployment.Container.Image; i != DefaultContainerImage {
+		t.Errorf("expected component container image to be defaulted to %q, got %q", DefaultContainerImage, i)
+	}
+	if l := myKind.Labels[DeploymentNameLabel]; l != "deployment-name" {
+		t.Errorf("expected %q label to be set to %q, got %q", DeploymentNameLabel, "deployment-name", l)
+	}
+	if myKind.Spec.DeletionPolicy != DefaultDeletionPolicy {
+		t.Errorf("expected deletion policy to be defaulted to %q, got %q", DefaultDeletionPolicy, myKind.Spec.DeletionPolicy)
+	}
+	if myKind.Spec.AdoptionPolicy != DefaultAdoptionPolicy {
+		t.Errorf("expected adoption policy to be defaulted to %q, got %q", DefaultAdoptionPolicy, myKind.Spec.AdoptionPolicy)
+	}
+	if myKind.Spec.WorkloadKind != DefaultWorkloadKind {
+		t.Errorf("expected workload kind to be defaulted to %q, got %q", DefaultWorkloadKind, myKind.Spec.WorkloadKind)
+	}
+	if l := myKind.Spec.RevisionHistoryLimit; l == nil || *l != DefaultRevisionHistoryLimit {
+		t.Errorf("expected revision history limit to be defaulted to %d, got %v", DefaultRevisionHistoryLimit, l)
+	}
+
+	myKind.Spec.Service = &ServiceSpec{Ports: []core.ServicePort{{Port: 80}}}
+	myKind.Default()
+
+	if myKind.Spec.Service.Type != core.ServiceTypeClusterIP {
+		t.Errorf("expected service type to be defaulted to %q, got %q", core.ServiceTypeClusterIP, myKind.Spec.Service.Type)
+	}
+	if tp := myKind.Spec.Service.Ports[0].TargetPort; tp != intstr.FromInt(80) {
+		t.Errorf("expected service targetPort to be defaulted to the port, got %v", tp.String())
+	}
+
+	myKind.Spec.Service.Ports[0].Name = "http"
+	myKind.Spec.Ingress = &IngressSpec{}
+	myKind.Default()
+
+	if p := myKind.Spec.Ingress.Paths; len(p) != 1 || p[0] != DefaultIngressPath {
+		t.Errorf("expected ingress paths to be defaulted to [%q], got %v", DefaultIngressPath, p)
+	}
+	if sp := myKind.Spec.Ingress.ServicePort; sp != intstr.FromString("http") {
+		t.Errorf("expected ingress servicePort to be defaulted to the first service port, got %v", sp.String())
+	}
+
+	myKind.Spec.Autoscaling = &AutoscalingSpec{MaxReplicas: 3}
+	myKind.Default()
+
+	if m := myKind.Spec.Autoscaling.MinReplicas; m == nil || *m != 1 {
+		t.Errorf("expected autoscaling minReplicas to be defaulted to 1, got %v", m)
+	}
+	if c := myKind.Spec.Autoscaling.TargetCPUUtilizationPercentage; c == nil || *c != DefaultTargetCPUUtilizationPercentage {
+		t.Errorf("expected autoscaling CPU target to be defaulted to %d, got %v", DefaultTargetCPUUtilizationPercentage, c)
+	}
+
+	myKind.Spec.Rollout = &RolloutSpec{BlueGreen: &BlueGreenStrategy{}}
+	myKind.Default()
+
+	if d := myKind.Spec.Rollout.BlueGreen.ScaleDownDelay; d == nil || d.Duration != DefaultScaleDownDelay {
+		t.Errorf("expected blue/green scaleDownDelay to be defaulted to %s, got %v", DefaultScaleDownDelay, d)
+	}
+
+	myKind.Spec.Deployment.Replicas = pointer.Int32Ptr(0)
+	myKind.Spec.Deployment.Container.Image = "busybox"
+	myKind.Spec.DeletionPolicy = DeletionPolicyOrphan
+	myKind.Default()
+
+	if *myKind.Spec.Deployment.Replicas != 0 {
+		t.Errorf("expected replicas to be left at 0, got %d", *myKind.Spec.Deployment.Replicas)
+	}
+	if myKind.Spec.Deployment.Container.Image != "busybox" {
+		t.Errorf("expected container image to be left as %q, got %q", "busybox", myKind.Spec.Deployment.Container.Image)
+	}
+	if myKind.Spec.DeletionPolicy != DeletionPolicyOrphan {
+		t.Errorf("expected deletion policy to be left as %q, got %q", DeletionPolicyOrphan, myKind.Spec.DeletionPolicy)
+	}
+}
+
+func TestValidateCreate(t *testing.T) {
+	existing := newTestMyKind("existing", "taken")
+	existingCanaryName := newTestMyKind("existing-canary-name", "web"+CanaryDeploymentSuffix)
+
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	webhookClient = fake.NewFakeClientWithScheme(scheme, existing, existingCanaryName)
+	defer func() { webhookClient = nil }()
+
+	tests := map[string]struct {
+		myKind  *MyKind
+		wantErr bool
+	}{
+		"valid resource": {
+			myKind: newTestMyKind("test", "deployment-name"),
+		},
+		"deploymentName that is not a DNS-1123 name": {
+			myKind:  newTestMyKind("test", "Not_Valid"),
+			wantErr: true,
+		},
+		"deploymentName used by another MyKind": {
+			myKind:  newTestMyKind("test", "taken"),
+			wantErr: true,
+		},
+		"deploymentName that is not a valid Service name": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "1web")
+				m.Spec.Service = &ServiceSpec{Ports: []core.ServicePort{{Port: 80}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"deploymentName with a dot and a service": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "a.b")
+				m.Spec.Service = &ServiceSpec{Ports: []core.ServicePort{{Port: 80}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"canary deployment name used by another MyKind": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "web")
+				m.Spec.Rollout = &RolloutSpec{Canary: &CanaryStrategy{Steps: []CanaryStep{{Weight: pointer.Int32Ptr(25)}}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"valid components": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Components = []ComponentSpec{
+					{Name: "worker", Deployment: DeploymentSpec{Name: "worker"}},
+					{Name: "scheduler", Deployment: DeploymentSpec{Name: "scheduler"}},
+				}
+				return m
+			}(),
+		},
+		"duplicate component names": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Components = []ComponentSpec{
+					{Name: "worker", Deployment: DeploymentSpec{Name: "worker-a"}},
+					{Name: "worker", Deployment: DeploymentSpec{Name: "worker-b"}},
+				}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"component name that is not a DNS-1123 label": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Components = []ComponentSpec{{Name: "Worker", Deployment: DeploymentSpec{Name: "worker"}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"component with the same deploymentName as the MyKind": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Components = []ComponentSpec{{Name: "worker", Deployment: DeploymentSpec{Name: "deployment-name"}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"component deploymentName used by another MyKind": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Components = []ComponentSpec{{Name: "worker", Deployment: DeploymentSpec{Name: "taken"}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"component with duplicate environment variables": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Components = []ComponentSpec{{Name: "worker", Deployment: DeploymentSpec{
+					Name:      "worker",
+					Container: ContainerSpec{Env: []core.EnvVar{{Name: "A", Value: "1"}, {Name: "A", Value: "2"}}},
+				}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"valid canary rollout": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Rollout = &RolloutSpec{Canary: &CanaryStrategy{Steps: []CanaryStep{
+					{Weight: pointer.Int32Ptr(25), Pause: &metav1.Duration{Duration: time.Minute}},
+					{Replicas: pointer.Int32Ptr(2), ManualPromotion: true},
+				}}}
+				return m
+			}(),
+		},
+		"canary rollout without steps": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Rollout = &RolloutSpec{Canary: &CanaryStrategy{}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"canary step with both weight and replicas": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Rollout = &RolloutSpec{Canary: &CanaryStrategy{Steps: []CanaryStep{
+					{Weight: pointer.Int32Ptr(25), Replicas: pointer.Int32Ptr(2)},
+				}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"canary step without weight or replicas": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Rollout = &RolloutSpec{Canary: &CanaryStrategy{Steps: []CanaryStep{{ManualPromotion: true}}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"canary step with a negative pause": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Rollout = &RolloutSpec{Canary: &CanaryStrategy{Steps: []CanaryStep{
+					{Weight: pointer.Int32Ptr(25), Pause: &metav1.Duration{Duration: -time.Minute}},
+				}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"canary rollout of a StatefulSet": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.WorkloadKind = WorkloadKindStatefulSet
+				m.Spec.Service = &ServiceSpec{Headless: true}
+				m.Spec.Rollout = &RolloutSpec{Canary: &CanaryStrategy{Steps: []CanaryStep{{Weight: pointer.Int32Ptr(25)}}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"canary rollout with autoscaling": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Autoscaling = &AutoscalingSpec{MaxReplicas: 3}
+				m.Spec.Rollout = &RolloutSpec{Canary: &CanaryStrategy{Steps: []CanaryStep{{Weight: pointer.Int32Ptr(25)}}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"component with the name of the canary Deployment": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Rollout = &RolloutSpec{Canary: &CanaryStrategy{Steps: []CanaryStep{{Weight: pointer.Int32Ptr(25)}}}}
+				m.Spec.Components = []ComponentSpec{{Name: "worker", Deployment: DeploymentSpec{Name: "deployment-name-canary"}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"valid blue/green rollout": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Service = &ServiceSpec{Ports: []core.ServicePort{{Port: 80}}}
+				m.Spec.Rollout = &RolloutSpec{BlueGreen: &BlueGreenStrategy{ScaleDownDelay: &metav1.Duration{Duration: time.Minute}}}
+				return m
+			}(),
+		},
+		"blue/green rollout without a service": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Rollout = &RolloutSpec{BlueGreen: &BlueGreenStrategy{}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"blue/green rollout with a negative scale down delay": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Service = &ServiceSpec{Ports: []core.ServicePort{{Port: 80}}}
+				m.Spec.Rollout = &RolloutSpec{BlueGreen: &BlueGreenStrategy{ScaleDownDelay: &metav1.Duration{Duration: -time.Minute}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"blue/green rollout with a preview Service name that is too long": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", strings.Repeat("a", 60))
+				m.Spec.Service = &ServiceSpec{Ports: []core.ServicePort{{Port: 80}}}
+				m.Spec.Rollout = &RolloutSpec{BlueGreen: &BlueGreenStrategy{}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"blue/green rollout of a StatefulSet": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.WorkloadKind = WorkloadKindStatefulSet
+				m.Spec.Service = &ServiceSpec{Headless: true}
+				m.Spec.Rollout = &RolloutSpec{BlueGreen: &BlueGreenStrategy{}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"blue/green rollout with autoscaling": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Service = &ServiceSpec{Ports: []core.ServicePort{{Port: 80}}}
+				m.Spec.Autoscaling = &AutoscalingSpec{MaxReplicas: 3}
+				m.Spec.Rollout = &RolloutSpec{BlueGreen: &BlueGreenStrategy{}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"valid schedules": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Schedules = []ScaleSchedule{
+					{Name: "overnight", Start: "0 20 * * *", End: "0 8 * * *", TimeZone: "Europe/London"},
+					{Name: "weekend", Start: "0 20 * * fri", End: "0 8 * * mon", Replicas: 1},
+				}
+				return m
+			}(),
+		},
+		"schedule with an invalid cron expression": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Schedules = []ScaleSchedule{{Name: "overnight", Start: "0 25 * * *", End: "0 8 * * *"}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"schedule with an unknown time zone": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Schedules = []ScaleSchedule{{Name: "overnight", Start: "0 20 * * *", End: "0 8 * * *", TimeZone: "Mars/Olympus_Mons"}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"duplicate schedule names": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Schedules = []ScaleSchedule{
+					{Name: "overnight", Start: "0 20 * * *", End: "0 8 * * *"},
+					{Name: "overnight", Start: "0 22 * * *", End: "0 6 * * *"},
+				}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"schedule of an unknown component": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Components = []ComponentSpec{{Name: "worker", Deployment: DeploymentSpec{Name: "worker"}}}
+				m.Spec.Schedules = []ScaleSchedule{{
+					Name: "overnight", Start: "0 20 * * *", End: "0 8 * * *",
+					Components: []ScheduledComponent{{Name: "worker"}, {Name: "cache"}},
+				}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"schedules with autoscaling": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Autoscaling = &AutoscalingSpec{MaxReplicas: 3}
+				m.Spec.Schedules = []ScaleSchedule{{Name: "overnight", Start: "0 20 * * *", End: "0 8 * * *"}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"blue/green and canary rollouts": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Service = &ServiceSpec{Ports: []core.ServicePort{{Port: 80}}}
+				m.Spec.Rollout = &RolloutSpec{
+					Canary:    &CanaryStrategy{Steps: []CanaryStep{{Weight: pointer.Int32Ptr(25)}}},
+					BlueGreen: &BlueGreenStrategy{},
+				}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"component with the name of a blue/green Deployment": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Service = &ServiceSpec{Ports: []core.ServicePort{{Port: 80}}}
+				m.Spec.Rollout = &RolloutSpec{BlueGreen: &BlueGreenStrategy{}}
+				m.Spec.Components = []ComponentSpec{{Name: "worker", Deployment: DeploymentSpec{Name: "deployment-name-green"}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"invalid container name": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Deployment.Container.Name = "Invalid_Name"
+				return m
+			}(),
+			wantErr: true,
+		},
+		"duplicate environment variables": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Deployment.Container.Env = []core.EnvVar{{Name: "A", Value: "1"}, {Name: "A", Value: "2"}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"invalid container port": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Deployment.Container.Ports = []core.ContainerPort{{ContainerPort: 70000}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"valid service": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Service = &ServiceSpec{Ports: []core.ServicePort{{Port: 80}}}
+				return m
+			}(),
+		},
+		"service without ports": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Service = &ServiceSpec{}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"headless service of type LoadBalancer": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Service = &ServiceSpec{Type: core.ServiceTypeLoadBalancer, Headless: true}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"valid ingress": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Service = &ServiceSpec{Ports: []core.ServicePort{{Name: "http", Port: 80}}}
+				m.Spec.Ingress = &IngressSpec{Hosts: []string{"example.com", "*.example.com"}, Paths: []string{"/"}, ServicePort: intstr.FromString("http")}
+				return m
+			}(),
+		},
+		"ingress without a service": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Ingress = &IngressSpec{}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"ingress with an unknown service port": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Service = &ServiceSpec{Ports: []core.ServicePort{{Name: "http", Port: 80}}}
+				m.Spec.Ingress = &IngressSpec{ServicePort: intstr.FromInt(8080)}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"ingress with a relative path": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Service = &ServiceSpec{Ports: []core.ServicePort{{Name: "http", Port: 80}}}
+				m.Spec.Ingress = &IngressSpec{Paths: []string{"api"}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"valid disruption budget": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				minAvailable := intstr.FromString("50%")
+				m.Spec.DisruptionBudget = &DisruptionBudgetSpec{MinAvailable: &minAvailable}
+				return m
+			}(),
+		},
+		"disruption budget with both minAvailable and maxUnavailable": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				value := intstr.FromInt(1)
+				m.Spec.DisruptionBudget = &DisruptionBudgetSpec{MinAvailable: &value, MaxUnavailable: &value}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"disruption budget with neither minAvailable nor maxUnavailable": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.DisruptionBudget = &DisruptionBudgetSpec{}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"disruption budget with an invalid percentage": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				maxUnavailable := intstr.FromString("150%")
+				m.Spec.DisruptionBudget = &DisruptionBudgetSpec{MaxUnavailable: &maxUnavailable}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"autoscaling with minReplicas greater than maxReplicas": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Autoscaling = &AutoscalingSpec{MinReplicas: pointer.Int32Ptr(3), MaxReplicas: 2}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"valid config references": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Deployment.ConfigMaps = []ConfigReference{{Name: "config", MountPath: "/etc/config"}}
+				m.Spec.Deployment.Secrets = []ConfigReference{{Name: "config"}}
+				return m
+			}(),
+		},
+		"config references with the same mount path": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Deployment.ConfigMaps = []ConfigReference{{Name: "config", MountPath: "/etc/config"}}
+				m.Spec.Deployment.Secrets = []ConfigReference{{Name: "secret", MountPath: "/etc/config"}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"duplicate config map references": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Deployment.ConfigMaps = []ConfigReference{{Name: "config"}, {Name: "config"}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"autoscaled daemon set": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.WorkloadKind = WorkloadKindDaemonSet
+				m.Spec.Autoscaling = &AutoscalingSpec{MinReplicas: pointer.Int32Ptr(1), MaxReplicas: 3}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"daemon set scaled to zero on deletion": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.WorkloadKind = WorkloadKindDaemonSet
+				m.Spec.DeletionPolicy = DeletionPolicyScaleToZero
+				return m
+			}(),
+			wantErr: true,
+		},
+		"stateful set": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.WorkloadKind = WorkloadKindStatefulSet
+				m.Spec.DeletionPolicy = DeletionPolicyScaleToZero
+				m.Spec.Service = &ServiceSpec{Headless: true}
+				return m
+			}(),
+		},
+		"stateful set without a service": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.WorkloadKind = WorkloadKindStatefulSet
+				return m
+			}(),
+			wantErr: true,
+		},
+		"stateful set with a service that is not headless": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.WorkloadKind = WorkloadKindStatefulSet
+				m.Spec.Service = &ServiceSpec{Ports: []core.ServicePort{{Name: "http", Port: 80}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+		"unnamed service ports": {
+			myKind: func() *MyKind {
+				m := newTestMyKind("test", "deployment-name")
+				m.Spec.Service = &ServiceSpec{Ports: []core.ServicePort{{Port: 80}, {Port: 443}}}
+				return m
+			}(),
+			wantErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.myKind.ValidateCreate()
+			if test.wantErr && err == nil {
+				t.Errorf("expected an error but got none")
+			}
+			if !test.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	deleting := newTestMyKind("test", "deployment-name")
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+
+	renamed := newTestMyKind("renamed", "deployment-name")
+	renamed.UID = "renamed-uid"
+	previous := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "previous-deployment-name",
+			Namespace:       renamed.Namespace,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(renamed, GroupVersion.WithKind("MyKind"))},
+		},
+	}
+	renamedAgain := renamed.DeepCopy()
+	renamedAgain.Spec.Deployment.Name = "new-deployment-name"
+
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := apps.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	webhookClient = fake.NewFakeClientWithScheme(scheme, previous)
+	defer func() { webhookClient = nil }()
+
+	tests := map[string]struct {
+		old, new *MyKind
+		wantErr  bool
+	}{
+		"changing deploymentName": {
+			old: newTestMyKind("test", "deployment-name"),
+			new: newTestMyKind("test", "new-deployment-name"),
+		},
+		"changing spec while being deleted": {
+			old:     deleting,
+			new:     newTestMyKind("test", "new-deployment-name"),
+			wantErr: true,
+		},
+		"changing deploymentName while a previous workload exists": {
+			old:     renamed,
+			new:     renamedAgain,
+			wantErr: true,
+		},
+		"changing other fields while a previous workload exists": {
+			old: renamed,
+			new: func() *MyKind {
+				m := renamed.DeepCopy()
+				m.Spec.Deployment.Container.Image = "busybox"
+				return m
+			}(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.new.ValidateUpdate(test.old)
+			if test.wantErr && err == nil {
+				t.Errorf("expected an error but got none")
+			}
+			if !test.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	v1 "jetstack.io/example-controller/api/v1"
+)
+
+// v1SpecAnnotation is set on a v1beta1 MyKind when it is converted from a
+// v1 MyKind whose spec cannot be fully represented in v1beta1. It holds
+// the JSON encoded v1 spec, so that the fields that would otherwise be lost
+// can be restored when converting back to v1.
+const v1SpecAnnotation = "mygroup.k8s.io/v1-spec"
+
+// v1StatusAnnotation is the equivalent of v1SpecAnnotation for the status
+// of a MyKind, and holds the JSON encoded v1 status.
+const v1StatusAnnotation = "mygroup.k8s.io/v1-status"
+
+var _ conversion.Convertible = &MyKind{}
+
+// ConvertTo converts this MyKind to the Hub version (v1).
+func (src *MyKind) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1.MyKind)
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	// Restore any fields that could not be represented the last time this
+	// resource was converted from v1. Fields that exist in v1beta1 are then
+	// set from src so that any changes made to them take precedence.
+	dst.Spec = v1.MyKindSpec{}
+	if data, ok := dst.Annotations[v1SpecAnnotation]; ok {
+		if err := json.Unmarshal([]byte(data), &dst.Spec); err != nil {
+			return err
+		}
+		delete(dst.Annotations, v1SpecAnnotation)
+	}
+
+	dst.Status = v1.MyKindStatus{}
+	if data, ok := dst.Annotations[v1StatusAnnotation]; ok {
+		if err := json.Unmarshal([]byte(data), &dst.Status); err != nil {
+			return err
+		}
+		delete(dst.Annotations, v1StatusAnnotation)
+	}
+
+	convertSpecToV1(&src.Spec, &dst.Spec)
+	convertStatusToV1(&src.Status, &dst.Status)
+
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1) to this version.
+func (dst *MyKind) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1.MyKind)
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	dst.Spec = MyKindSpec{}
+	convertSpecFromV1(&src.Spec, &dst.Spec)
+	convertStatusFromV1(&src.Status, &dst.Status)
+
+	// If converting back to v1 would lose information, preserve the v1 spec
+	// and status in annotations.
+	restoredSpec := v1.MyKindSpec{}
+	convertSpecToV1(&dst.Spec, &restoredSpec)
+	if !apiequality.Semantic.DeepEqual(restoredSpec, src.Spec) {
+		if err := setJSONAnnotation(dst, v1SpecAnnotation, src.Spec); err != nil {
+			return err
+		}
+	}
+
+	restoredStatus := v1.MyKindStatus{}
+	convertStatusToV1(&dst.Status, &restoredStatus)
+	if !apiequality.Semantic.DeepEqual(restoredStatus, src.Status) {
+		if err := setJSONAnnotation(dst, v1StatusAnnotation, src.Status); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setJSONAnnotation sets the annotation key of the given MyKind to the JSON
+// encoding of v.
+func setJSONAnnotation(myKind *MyKind, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if myKind.Annotations == nil {
+		myKind.Annotations = map[string]string{}
+	}
+	myKind.Annotations[key] = string(data)
+	return nil
+}
+
+// convertSpecToV1 sets the fields of dst that are represented in v1beta1.
+// Fields that only exist in v1 are left untouched.
+func convertSpecToV1(src *MyKindSpec, dst *v1.MyKindSpec) {
+	dst.Deployment.Name = src.DeploymentName
+	dst.Deployment.Replicas = src.Replicas
+
+	container := &dst.Deployment.Container
+	container.Name = src.Container.Name
+	container.Image = src.Container.Image
+	container.Command = src.Container.Command
+	container.Args = src.Container.Args
+	container.Env = src.Container.Env
+	container.Ports = src.Container.Ports
+	container.Resources = src.Container.Resources
+}
+
+func convertSpecFromV1(src *v1.MyKindSpec, dst *MyKindSpec) {
+	dst.DeploymentName = src.Deployment.Name
+	dst.Replicas = src.Deployment.Replicas
+
+	container := src.Deployment.Container
+	dst.Container.Name = container.Name
+	dst.Container.Image = container.Image
+	dst.Container.Command = container.Command
+	dst.Container.Args = container.Args
+	dst.Container.Env = container.Env
+	dst.Container.Ports = container.Ports
+	dst.Container.Resources = container.Resources
+}
+
+func convertStatusToV1(src *MyKindStatus, dst *v1.MyKindStatus) {
+	dst.ObservedGeneration = src.ObservedGeneration
+	dst.DeploymentName = src.DeploymentName
+	dst.Replicas = src.Replicas
+	dst.Selector = src.Selector
+	dst.ReadyReplicas = src.ReadyReplicas
+	dst.UpdatedReplicas = src.UpdatedReplicas
+	dst.AvailableReplicas = src.AvailableReplicas
+	dst.UnavailableReplicas = src.UnavailableReplicas
+
+	dst.Conditions = nil
+	for _, c := range src.Conditions {
+		dst.Conditions = append(dst.Conditions, v1.MyKindCondition{
+			Type:               v1.MyKindConditionType(c.Type),
+			Status:             c.Status,
+			ObservedGeneration: c.ObservedGeneration,
+			LastTransitionTime: c.LastTransitionTime,
+			Reason:             c.Reason,
+			Message:            c.Message,
+		})
+	}
+}
+
+func convertStatusFromV1(src *v1.MyKindStatus, dst *MyKindStatus) {
+	dst.ObservedGeneration = src.ObservedGeneration
+	dst.DeploymentName = src.DeploymentName
+	dst.Replicas = src.Replicas
+	dst.Selector = src.Selector
+	dst.ReadyReplicas = src.ReadyReplicas
+	dst.UpdatedReplicas = src.UpdatedReplicas
+	dst.AvailableReplicas = src.AvailableReplicas
+	dst.UnavailableReplicas = src.UnavailableReplicas
+
+	dst.Conditions = nil
+	for _, c := range src.Conditions {
+		dst.Conditions = append(dst.Conditions, MyKindCondition{
+			Type:               MyKindConditionType(c.Type),
+			Status:             c.Status,
+			ObservedGeneration: c.ObservedGeneration,
+			LastTransitionTime: c.LastTransitionTime,
+			Reason:             c.Reason,
+			Message:            c.Message,
+		})
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+
+	fuzz "github.com/google/gofuzz"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/diff"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "jetstack.io/example-controller/api/v1"
+)
+
+const fuzzIterations = 1000
+
+func newFuzzer() *fuzz.Fuzzer {
+	return fuzz.New().NilChance(0.2).NumElements(0, 3).Funcs(
+		// Quantities have internal state that the fuzzer would otherwise
+		// fill with values that do not survive JSON encoding.
+		func(q *resource.Quantity, c fuzz.Continue) {
+			*q = *resource.NewQuantity(c.Int63n(1000), resource.DecimalSI)
+		},
+		// TypeMeta is set by the conversion webhook rather than by the
+		// conversion functions, and only the fields of ObjectMeta that
+		// conversion cares about are fuzzed.
+		func(*metav1.TypeMeta, fuzz.Continue) {},
+		func(m *metav1.ObjectMeta, c fuzz.Continue) {
+			c.Fuzz(&m.Name)
+			c.Fuzz(&m.Namespace)
+			c.Fuzz(&m.Labels)
+			c.Fuzz(&m.Annotations)
+			delete(m.Annotations, v1SpecAnnotation)
+			delete(m.Annotations, v1StatusAnnotation)
+		},
+		func(t *metav1.Time, c fuzz.Continue) {
+			*t = metav1.Unix(c.Int63n(1<<32), 0)
+		},
+		func(i *intstr.IntOrString, c fuzz.Continue) {
+			if c.RandBool() {
+				*i = intstr.FromInt(c.Intn(65536))
+			} else {
+				*i = intstr.FromString(c.RandString())
+			}
+		},
+	)
+}
+
+func TestConversionRoundTripFromV1beta1(t *testing.T) {
+	f := newFuzzer()
+	for i := 0; i < fuzzIterations; i++ {
+		original := &MyKind{}
+		f.Fuzz(original)
+
+		hub := &v1.MyKind{}
+		if err := original.DeepCopy().ConvertTo(hub); err != nil {
+			t.Fatalf("failed to convert to v1: %v", err)
+		}
+
+		roundTripped := &MyKind{}
+		if err := roundTripped.ConvertFrom(hub); err != nil {
+			t.Fatalf("failed to convert from v1: %v", err)
+		}
+
+		if !apiequality.Semantic.DeepEqual(original, roundTripped) {
+			t.Fatalf("v1beta1 -> v1 -> v1beta1 round trip was lossy:\n%s", diff.ObjectReflectDiff(original, roundTripped))
+		}
+	}
+}
+
+func TestConversionRoundTripFromV1(t *testing.T) {
+	f := newFuzzer()
+	for i := 0; i < fuzzIterations; i++ {
+		original := &v1.MyKind{}
+		f.Fuzz(original)
+
+		spoke := &MyKind{}
+		if err := spoke.ConvertFrom(original.DeepCopy()); err != nil {
+			t.Fatalf("failed to convert from v1: %v", err)
+		}
+
+		roundTripped := &v1.MyKind{}
+		if err := spoke.ConvertTo(roundTripped); err != nil {
+			t.Fatalf("failed to convert to v1: %v", err)
+		}
+
+		if !apiequality.Semantic.DeepEqual(original, roundTripped) {
+			t.Fatalf("v1 -> v1beta1 -> v1 round trip was lossy:\n%s", diff.ObjectReflectDiff(original, roundTripped))
+		}
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package v1beta1
 
 import (
+	core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -35,25 +36,169 @@ type MyKindSpec struct {
 
 	// Replicas is the number of replicas that should be specified on the
 	// Deployment resource that the controller creates.
-	// If not specified, one replica will be created.
+	// If not specified, it will be defaulted to one replica.
 	// +optional
 	// +kubebuilder:validation:Minimum=0
 	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Container describes the container that will be run in each pod of
+	// the Deployment resource that the controller creates.
+	// If not specified, a single nginx:latest container will be run.
+	// +optional
+	Container ContainerSpec `json:"container,omitempty"`
+}
+
+// ContainerSpec describes the container that is run in each pod of the
+// Deployment created for a MyKind resource.
+type ContainerSpec struct {
+	// Name is the name of the container.
+	// If not specified, 'nginx' will be used.
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?$
+	Name string `json:"name,omitempty"`
+
+	// Image is the container image to run.
+	// If not specified, 'nginx:latest' will be used.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Command is the entrypoint array. The image's ENTRYPOINT is used if
+	// this is not provided.
+	// +optional
+	Command []string `json:"command,omitempty"`
+
+	// Args are the arguments to the entrypoint. The image's CMD is used if
+	// this is not provided.
+	// +optional
+	Args []string `json:"args,omitempty"`
+
+	// Env is a list of environment variables to set in the container.
+	// +optional
+	Env []core.EnvVar `json:"env,omitempty"`
+
+	// Ports is a list of ports to expose from the container.
+	// +optional
+	Ports []core.ContainerPort `json:"ports,omitempty"`
+
+	// Resources are the compute resources required by the container.
+	// +optional
+	Resources core.ResourceRequirements `json:"resources,omitempty"`
 }
 
 // MyKindStatus defines the observed state of MyKind
 type MyKindStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 
+	// ObservedGeneration is the most recent generation of the MyKind
+	// resource that has been observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// DeploymentName is the name of the Deployment resource currently
+	// managed for this MyKind resource.
+	// +optional
+	DeploymentName string `json:"deploymentName,omitempty"`
+
+	// Replicas is the total number of replicas observed on the Deployment
+	// resource created for this MyKind resource.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Selector is the label selector, in string form, that matches the pods
+	// of the Deployment resource created for this MyKind resource.
+	// It is used by the scale subresource.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
 	// ReadyReplicas is the number of 'ready' replicas observed on the
 	// Deployment resource created for this MyKind resource.
 	// +optional
 	// +kubebuilder:validation:Minimum=0
 	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// UpdatedReplicas is the number of replicas observed on the Deployment
+	// resource that are running the most recent pod template.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+
+	// AvailableReplicas is the number of 'available' replicas observed on
+	// the Deployment resource created for this MyKind resource.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+
+	// UnavailableReplicas is the number of replicas observed on the
+	// Deployment resource that are not yet available.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	UnavailableReplicas int32 `json:"unavailableReplicas,omitempty"`
+
+	// Conditions represent the latest available observations of the
+	// MyKind resource's state.
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []MyKindCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// MyKindConditionType is the type of a condition on a MyKind resource.
+type MyKindConditionType string
+
+const (
+	// MyKindReady is True when the MyKind resource has been fully
+	// reconciled and its Deployment is available and not progressing.
+	MyKindReady MyKindConditionType = "Ready"
+
+	// MyKindProgressing is True while the Deployment for the MyKind
+	// resource is being created, scaled or rolled out.
+	MyKindProgressing MyKindConditionType = "Progressing"
+
+	// MyKindDegraded is True when the controller failed to reconcile the
+	// MyKind resource or its Deployment has failed to make progress.
+	MyKindDegraded MyKindConditionType = "Degraded"
+
+	// MyKindDeploymentAvailable mirrors the Available condition of the
+	// Deployment managed for the MyKind resource.
+	MyKindDeploymentAvailable MyKindConditionType = "DeploymentAvailable"
+)
+
+// MyKindCondition describes the state of a MyKind resource at a certain
+// point.
+type MyKindCondition struct {
+	// Type of the condition.
+	Type MyKindConditionType `json:"type"`
+
+	// Status of the condition, one of True, False or Unknown.
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status core.ConditionStatus `json:"status"`
+
+	// ObservedGeneration is the generation of the MyKind resource that the
+	// condition was set based upon.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastTransitionTime is the last time the condition transitioned from
+	// one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Reason is a brief CamelCase reason for the condition's last
+	// transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable message indicating details about the
+	// last transition.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
 
 // MyKind is the Schema for the mykinds API
 type MyKind struct {
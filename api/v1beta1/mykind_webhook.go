@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	v1 "jetstack.io/example-controller/api/v1"
+)
+
+// log is for logging in this package.
+var mykindlog = logf.Log.WithName("mykind-resource")
+
+// SetupWebhookWithManager registers the webhooks for MyKind with the
+// manager's webhook server. This includes the conversion webhook, as MyKind
+// implements conversion.Convertible.
+func (r *MyKind) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// The defaulting and validation rules for MyKind are implemented on the hub
+// version (v1). The webhooks for v1beta1 convert the resource to v1 and
+// delegate to it, so that both versions are treated the same way.
+
+// +kubebuilder:webhook:path=/mutate-mygroup-k8s-io-v1beta1-mykind,mutating=true,failurePolicy=fail,groups=mygroup.k8s.io,resources=mykinds,verbs=create;update,versions=v1beta1,name=mmykind-v1beta1.kb.io
+
+var _ webhook.Defaulter = &MyKind{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *MyKind) Default() {
+	_, preserved := r.Annotations[v1SpecAnnotation]
+
+	hub := &v1.MyKind{}
+	if err := r.ConvertTo(hub); err != nil {
+		mykindlog.Error(err, "failed to convert to v1 for defaulting", "name", r.Name)
+		return
+	}
+
+	hub.Default()
+
+	if err := r.ConvertFrom(hub); err != nil {
+		mykindlog.Error(err, "failed to convert from v1 after defaulting", "name", r.Name)
+		return
+	}
+
+	// Defaulting sets fields that only exist in v1, which would otherwise be
+	// preserved in the v1 spec annotation of every v1beta1 MyKind. Unless
+	// the annotation already held values set using v1, it only holds
+	// defaults, which are set again whenever the MyKind is defaulted as v1.
+	if !preserved {
+		delete(r.Annotations, v1SpecAnnotation)
+	}
+}
+
+// +kubebuilder:webhook:path=/validate-mygroup-k8s-io-v1beta1-mykind,mutating=false,failurePolicy=fail,groups=mygroup.k8s.io,resources=mykinds,verbs=create;update,versions=v1beta1,name=vmykind-v1beta1.kb.io
+
+var _ webhook.Validator = &MyKind{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *MyKind) ValidateCreate() error {
+	hub := &v1.MyKind{}
+	if err := r.ConvertTo(hub); err != nil {
+		return err
+	}
+
+	return hub.ValidateCreate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *MyKind) ValidateUpdate(old runtime.Object) error {
+	hub := &v1.MyKind{}
+	if err := r.ConvertTo(hub); err != nil {
+		return err
+	}
+
+	oldHub := &v1.MyKind{}
+	if err := old.(*MyKind).ConvertTo(oldHub); err != nil {
+		return err
+	}
+
+	return hub.ValidateUpdate(oldHub)
+}
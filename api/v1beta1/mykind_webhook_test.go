@@ -0,0 +1,66 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "jetstack.io/example-controller/api/v1"
+)
+
+func TestDefault(t *testing.T) {
+	myKind := &MyKind{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+		Spec: MyKindSpec{
+			DeploymentName: "deployment-name",
+		},
+	}
+	myKind.Default()
+
+	if r := myKind.Spec.Replicas; r == nil || *r != v1.DefaultReplicas {
+		t.Errorf("expected replicas to be defaulted to %d, got %v", v1.DefaultReplicas, r)
+	}
+	if myKind.Spec.Container.Image != v1.DefaultContainerImage {
+		t.Errorf("expected container image to be defaulted to %q, got %q", v1.DefaultContainerImage, myKind.Spec.Container.Image)
+	}
+	if _, ok := myKind.Annotations[v1SpecAnnotation]; ok {
+		t.Errorf("expected no %s annotation when only defaults are set, got %q", v1SpecAnnotation, myKind.Annotations[v1SpecAnnotation])
+	}
+
+	// Values set using v1 are still preserved.
+	hub := &v1.MyKind{}
+	if err := myKind.ConvertTo(hub); err != nil {
+		t.Fatal(err)
+	}
+	hub.Spec.DeletionPolicy = v1.DeletionPolicyOrphan
+	if err := myKind.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	myKind.Default()
+
+	if err := myKind.ConvertTo(hub); err != nil {
+		t.Fatal(err)
+	}
+	if hub.Spec.DeletionPolicy != v1.DeletionPolicyOrphan {
+		t.Errorf("expected deletion policy %q set using v1 to be preserved, got %q", v1.DeletionPolicyOrphan, hub.Spec.DeletionPolicy)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// deploymentNameConflictError is returned when a Deployment named in the
+// spec of a MyKind exists but cannot be adopted by it.
+type deploymentNameConflictError struct {
+	// reason is a CamelCase reason for the conflict, used as the reason of
+	// the DeploymentNameConflict condition.
+	reason  string
+	message string
+}
+
+func (e *deploymentNameConflictError) Error() string {
+	return e.message
+}
+
+// adoptDeployment makes the given MyKind the controller of an existing
+// Deployment that it does not control, if its adoption policy permits it
+// and the Deployment's selector is compatible with the pods that the
+// MyKind would create.
+// A *deploymentNameConflictError is returned if the Deployment cannot be
+// adopted.
+func (r *MyKindReconciler) adoptDeployment(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, deployment *apps.Deployment) error {
+	owner := metav1.GetControllerOf(deployment)
+
+	switch myKind.Spec.AdoptionPolicy {
+	case mygroupv1.AdoptionPolicyForce:
+	case mygroupv1.AdoptionPolicyIfUnowned:
+		if owner != nil {
+			return &deploymentNameConflictError{
+				reason: "DeploymentControlledByOther",
+				message: fmt.Sprintf("Deployment %q is controlled by %s %q and adoptionPolicy is %s",
+					deployment.Name, owner.Kind, owner.Name, myKind.Spec.AdoptionPolicy),
+			}
+		}
+	default:
+		return &deploymentNameConflictError{
+			reason: "AdoptionDisabled",
+			message: fmt.Sprintf("Deployment %q already exists and is not controlled by this MyKind, and adoptionPolicy is %s",
+				deployment.Name, mygroupv1.AdoptionPolicyNever),
+		}
+	}
+
+	if !selectorCompatible(deployment.Spec.Selector, buildPodTemplate(*myKind, "")) {
+		return &deploymentNameConflictError{
+			reason: "SelectorIncompatible",
+			message: fmt.Sprintf("Deployment %q has selector %q which does not match the pods of this MyKind",
+				deployment.Name, metav1.FormatLabelSelector(deployment.Spec.Selector)),
+		}
+	}
+
+	log.Info("adopting existing Deployment", "previous_controller", owner)
+
+	base := deployment.DeepCopy()
+	var refs []metav1.OwnerReference
+	for _, ref := range deployment.OwnerReferences {
+		if ref.Controller == nil || !*ref.Controller {
+			refs = append(refs, ref)
+		}
+	}
+	deployment.OwnerReferences = append(refs, *metav1.NewControllerRef(myKind, mygroupv1.GroupVersion.WithKind("MyKind")))
+	if err := r.patch(ctx, deployment, base); err != nil {
+		log.Error(err, "failed to adopt Deployment")
+		return err
+	}
+
+	r.Recorder.Eventf(myKind, core.EventTypeNormal, "Adopted", "Adopted deployment %q", deployment.Name)
+
+	return nil
+}
+
+// selectorCompatible returns true if the selector of an existing workload
+// selects the pods of the desired pod template.
+// The selector of a workload is immutable, so an incompatible selector can
+// only be corrected by recreating the workload.
+func selectorCompatible(existing *metav1.LabelSelector, desired *core.PodTemplateSpec) bool {
+	selector, err := metav1.LabelSelectorAsSelector(existing)
+	if err != nil || selector.Empty() {
+		return false
+	}
+	return selector.Matches(labels.Set(desired.Labels))
+}
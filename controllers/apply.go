@@ -0,0 +1,94 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fieldManager is the field manager that the controller applies the
+// resources it manages as.
+const fieldManager = "mykind-controller"
+
+// apply creates or updates obj using server-side apply, so that only the
+// fields set on obj are owned by the controller and fields set by other
+// field managers are left untouched. obj must have its apiVersion and kind
+// set, and is updated with the resource returned by the apiserver.
+// If ForceOwnership is not set, applying a field that is owned by another
+// field manager and set to a different value fails with a conflict error.
+// Server-side apply is alpha in Kubernetes 1.14 and 1.15, so the apiserver
+// must be run with the ServerSideApply feature gate enabled.
+func (r *MyKindReconciler) apply(ctx context.Context, obj runtime.Object) error {
+	opts := []client.PatchOptionFunc{client.FieldOwner(fieldManager)}
+	if r.ForceOwnership {
+		opts = append(opts, client.ForceOwnership)
+	}
+	return r.Client.Patch(ctx, obj, client.Apply, opts...)
+}
+
+// patch sends a JSON merge patch of the changes made to obj since it was
+// read as base, instead of updating the whole object, so that fields that
+// the controller did not change are left untouched.
+// Lists such as metadata.ownerReferences are replaced as a whole by a merge
+// patch, so the patch is conditional on the resourceVersion of base and
+// fails with a conflict error if obj has been modified since.
+func (r *MyKindReconciler) patch(ctx context.Context, obj, base runtime.Object) error {
+	return r.Client.Patch(ctx, obj, &optimisticMergePatch{from: base})
+}
+
+// optimisticMergePatch is a client.Patch that merge patches an object with
+// the changes made to it since from, with the resourceVersion of from as a
+// precondition.
+type optimisticMergePatch struct {
+	from runtime.Object
+}
+
+// Type implements client.Patch.
+func (p *optimisticMergePatch) Type() types.PatchType {
+	return types.MergePatchType
+}
+
+// Data implements client.Patch.
+func (p *optimisticMergePatch) Data(obj runtime.Object) ([]byte, error) {
+	data, err := client.MergeFrom(p.from).Data(obj)
+	if err != nil {
+		return nil, err
+	}
+	accessor, err := meta.Accessor(p.from)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := map[string]interface{}{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, err
+	}
+	metadata, _ := patch["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["resourceVersion"] = accessor.GetResourceVersion()
+	patch["metadata"] = metadata
+
+	return json.Marshal(patch)
+}
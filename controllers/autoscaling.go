@@ -0,0 +1,200 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	core "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// reconcileHorizontalPodAutoscaler ensures that the HorizontalPodAutoscaler
+// for the given MyKind exists and is up to date if spec.autoscaling is set,
+// and cleans up any HorizontalPodAutoscalers previously created for it.
+func (r *MyKindReconciler) reconcileHorizontalPodAutoscaler(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) error {
+	if err := r.cleanupOwnedHorizontalPodAutoscalers(ctx, log, myKind); err != nil {
+		log.Error(err, "failed to clean up old HorizontalPodAutoscaler resources for this MyKind")
+		return err
+	}
+
+	if myKind.Spec.Autoscaling == nil {
+		return nil
+	}
+
+	log = log.WithValues("hpa_name", myKind.Spec.Deployment.Name)
+
+	log.Info("checking if an existing HorizontalPodAutoscaler exists for this resource")
+	hpa := autoscalingv2beta2.HorizontalPodAutoscaler{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: myKind.Namespace, Name: myKind.Spec.Deployment.Name}, &hpa)
+	if apierrors.IsNotFound(err) {
+		log.Info("could not find existing HorizontalPodAutoscaler for MyKind, creating one...")
+
+		hpa = *buildHorizontalPodAutoscaler(*myKind)
+		if err := r.apply(ctx, &hpa); err != nil {
+			log.Error(err, "failed to create HorizontalPodAutoscaler resource")
+			return err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Created", "Created horizontal pod autoscaler %q", hpa.Name)
+		log.Info("created HorizontalPodAutoscaler resource for MyKind")
+		return nil
+	}
+	if err != nil {
+		log.Error(err, "failed to get HorizontalPodAutoscaler for MyKind resource")
+		return err
+	}
+
+	if !metav1.IsControlledBy(&hpa, myKind) {
+		return fmt.Errorf("horizontal pod autoscaler %q already exists and is not controlled by this MyKind", hpa.Name)
+	}
+
+	log.Info("checking the HorizontalPodAutoscaler for drift")
+	desired := buildHorizontalPodAutoscaler(*myKind)
+	if corrected := correctHorizontalPodAutoscalerDrift(hpa.DeepCopy(), desired); len(corrected) > 0 {
+		log.Info("applying HorizontalPodAutoscaler", "fields", corrected)
+
+		if err := r.apply(ctx, desired); err != nil {
+			log.Error(err, "failed to apply HorizontalPodAutoscaler")
+			return err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "DriftCorrected", "Corrected drift in horizontal pod autoscaler %q: %s", hpa.Name, strings.Join(corrected, ", "))
+
+		return nil
+	}
+
+	log.Info("HorizontalPodAutoscaler up to date")
+
+	return nil
+}
+
+// cleanupOwnedHorizontalPodAutoscalers will Delete any existing
+// HorizontalPodAutoscaler resources that were created for the given MyKind
+// that no longer match the myKind.spec.deployment.name field, or all of them
+// if myKind.spec.autoscaling is not set.
+func (r *MyKindReconciler) cleanupOwnedHorizontalPodAutoscalers(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) error {
+	log.Info("finding existing HorizontalPodAutoscalers for MyKind resource")
+
+	// List all horizontal pod autoscaler resources owned by this MyKind
+	var hpas autoscalingv2beta2.HorizontalPodAutoscalerList
+	if err := r.List(ctx, &hpas, client.InNamespace(myKind.Namespace), client.MatchingField(hpaOwnerKey, myKind.Name)); err != nil {
+		return err
+	}
+
+	deleted := 0
+	for _, hpa := range hpas.Items {
+		if myKind.Spec.Autoscaling != nil && hpa.Name == myKind.Spec.Deployment.Name {
+			// If this horizontal pod autoscaler's name matches the one on
+			// the MyKind resource then do not delete it.
+			continue
+		}
+
+		if err := r.Client.Delete(ctx, &hpa); err != nil {
+			log.Error(err, "failed to delete HorizontalPodAutoscaler resource")
+			return err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Deleted", "Deleted horizontal pod autoscaler %q", hpa.Name)
+		deleted++
+	}
+
+	log.Info("finished cleaning up old HorizontalPodAutoscaler resources", "number_deleted", deleted)
+
+	return nil
+}
+
+// buildHorizontalPodAutoscaler renders the HorizontalPodAutoscaler for the
+// given MyKind, which must have spec.autoscaling set, targeting the
+// workload created for it.
+func buildHorizontalPodAutoscaler(myKind mygroupv1.MyKind) *autoscalingv2beta2.HorizontalPodAutoscaler {
+	spec := myKind.Spec.Autoscaling.DeepCopy()
+
+	var metrics []autoscalingv2beta2.MetricSpec
+	if spec.TargetCPUUtilizationPercentage != nil {
+		metrics = append(metrics, resourceUtilizationMetric(core.ResourceCPU, *spec.TargetCPUUtilizationPercentage))
+	}
+	if spec.TargetMemoryUtilizationPercentage != nil {
+		metrics = append(metrics, resourceUtilizationMetric(core.ResourceMemory, *spec.TargetMemoryUtilizationPercentage))
+	}
+
+	hpa := autoscalingv2beta2.HorizontalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: autoscalingv2beta2.SchemeGroupVersion.String(),
+			Kind:       "HorizontalPodAutoscaler",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            myKind.Spec.Deployment.Name,
+			Namespace:       myKind.Namespace,
+			Labels:          deploymentLabels(myKind),
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(&myKind, mygroupv1.GroupVersion.WithKind("MyKind"))},
+		},
+		Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       string(workloadKind(myKind)),
+				Name:       myKind.Spec.Deployment.Name,
+			},
+			MinReplicas: spec.MinReplicas,
+			MaxReplicas: spec.MaxReplicas,
+			Metrics:     metrics,
+		},
+	}
+	return &hpa
+}
+
+func resourceUtilizationMetric(name core.ResourceName, percentage int32) autoscalingv2beta2.MetricSpec {
+	return autoscalingv2beta2.MetricSpec{
+		Type: autoscalingv2beta2.ResourceMetricSourceType,
+		Resource: &autoscalingv2beta2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2beta2.MetricTarget{
+				Type:               autoscalingv2beta2.UtilizationMetricType,
+				AverageUtilization: &percentage,
+			},
+		},
+	}
+}
+
+// correctHorizontalPodAutoscalerDrift compares the fields of an existing
+// HorizontalPodAutoscaler that are owned by the controller against the
+// desired HorizontalPodAutoscaler built for a MyKind, and copies the
+// desired values onto existing wherever they differ.
+// It returns the path of each field that was corrected.
+func correctHorizontalPodAutoscalerDrift(existing, desired *autoscalingv2beta2.HorizontalPodAutoscaler) []string {
+	var corrected []string
+
+	if !labelsUpToDate(existing.Labels, desired.Labels) {
+		existing.Labels = mergeLabels(existing.Labels, desired.Labels)
+		corrected = append(corrected, "metadata.labels")
+	}
+	if !apiequality.Semantic.DeepEqual(existing.Spec, desired.Spec) {
+		existing.Spec = desired.Spec
+		corrected = append(corrected, "spec")
+	}
+
+	return corrected
+}
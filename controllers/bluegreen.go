@@ -0,0 +1,267 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// reconcileBlueGreen ensures that the Deployments of the given MyKind, which
+// is rolled out with spec.rollout.blueGreen, exist and are up to date.
+// The active Deployment runs the pod template that the Service routes
+// traffic to. A pod template that differs from it is run in a preview
+// Deployment of the other colour, which becomes the active Deployment when
+// it is promoted. The previously active Deployment is deleted once the
+// scale down delay has passed. The state of the rollout is read from the
+// status of the MyKind, and the new state is returned for it to be written
+// back.
+// desired is the Deployment built for the MyKind.
+// It returns the active and preview Deployments as last observed or written
+// by the controller.
+func (r *MyKindReconciler) reconcileBlueGreen(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, desired *apps.Deployment) (observedState, ctrl.Result, error) {
+	var observed observedState
+	var result ctrl.Result
+	strategy := myKind.Spec.Rollout.BlueGreen
+	now := metav1.NewTime(r.now())
+
+	state := &mygroupv1.BlueGreenStatus{}
+	if myKind.Status.BlueGreen != nil {
+		state = myKind.Status.BlueGreen.DeepCopy()
+	}
+	observed.blueGreen = state
+
+	active := &apps.Deployment{}
+	activeFound := false
+	if state.ActiveColor != "" {
+		var err error
+		activeFound, err = r.getControlledByName(ctx, myKind, colorDeploymentName(*myKind, state.ActiveColor), active)
+		if err != nil {
+			log.Error(err, "failed to get active Deployment for MyKind resource")
+			return observed, result, err
+		}
+	}
+	previewColor := otherColor(state.ActiveColor)
+	preview := &apps.Deployment{}
+	previewFound, err := r.getControlledByName(ctx, myKind, colorDeploymentName(*myKind, previewColor), preview)
+	if err != nil {
+		log.Error(err, "failed to get preview Deployment for MyKind resource")
+		return observed, result, err
+	}
+
+	desiredActive := buildColorDeployment(*desired, state.ActiveColor)
+	if state.ActiveColor != "" && (!activeFound || len(correctPodTemplateDrift(active.Spec.Template.DeepCopy(), &desiredActive.Spec.Template)) == 0) {
+		// An active Deployment that has been deleted is created again as
+		// it is, as the Service already routes traffic to it.
+		log.Info("no blue/green rollout in progress", "active_color", state.ActiveColor)
+		state.PreviewColor = ""
+		state.PreviewAvailable = false
+		observed.workload, result, err = r.reconcileWorkloadObject(ctx, log, myKind, desiredActive)
+		if err != nil {
+			return observed, result, err
+		}
+		if err := r.clearPromotion(ctx, myKind); err != nil {
+			log.Error(err, "failed to remove promote annotation from MyKind resource")
+			return observed, result, err
+		}
+		if !previewFound {
+			state.ScaleDownTime = nil
+			return observed, result, nil
+		}
+
+		// A preview Deployment whose pod template has been reverted is
+		// deleted straight away, while a previously active Deployment is
+		// kept until the scale down delay has passed.
+		if state.ScaleDownTime != nil {
+			if remaining := state.ScaleDownTime.Sub(now.Time); remaining > 0 {
+				log.Info("waiting for scale down delay before deleting previously active Deployment", "remaining", remaining)
+				observed.previewDeployment = preview
+				result.RequeueAfter = remaining
+				return observed, result, nil
+			}
+		}
+		state.ScaleDownTime = nil
+		return observed, result, r.deleteDeployment(ctx, log, myKind, preview)
+	}
+
+	log = log.WithValues("active_color", state.ActiveColor, "preview_color", previewColor)
+	if state.PreviewColor == "" {
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "BlueGreenStarted", "Started blue/green rollout of deployment %q", colorDeploymentName(*myKind, previewColor))
+	}
+	state.PreviewColor = previewColor
+	// A previously active Deployment that has not yet been deleted is
+	// reused for the preview.
+	state.ScaleDownTime = nil
+
+	if activeFound {
+		// The active Deployment keeps its pod template, but follows
+		// changes to the replica count.
+		if err := r.scaleDeployment(ctx, log, myKind, active, *desired.Spec.Replicas); err != nil {
+			return observed, result, err
+		}
+	}
+
+	previewWorkload, result, err := r.reconcileWorkloadObject(ctx, log, myKind, buildColorDeployment(*desired, previewColor))
+	if activeFound {
+		observed.workload = active
+		if previewWorkload != nil {
+			observed.previewDeployment = previewWorkload.(*apps.Deployment)
+		}
+	} else {
+		// Until there is an active Deployment the status describes the
+		// preview Deployment.
+		observed.workload = previewWorkload
+	}
+	if err != nil {
+		return observed, result, err
+	}
+
+	if summary := summarizeWorkload(previewWorkload); summary == nil || !summary.complete {
+		log.Info("waiting for preview replicas to become available")
+		state.PreviewAvailable = false
+		return observed, result, nil
+	}
+	state.PreviewAvailable = true
+
+	// The first Deployment is promoted as soon as it is available, as there
+	// is nothing for it to be compared against.
+	if state.ActiveColor != "" && !strategy.AutoPromote && myKind.Annotations[mygroupv1.PromoteAnnotation] != "true" {
+		log.Info("preview Deployment is awaiting promotion")
+		return observed, result, nil
+	}
+	if err := r.clearPromotion(ctx, myKind); err != nil {
+		log.Error(err, "failed to remove promote annotation from MyKind resource")
+		return observed, result, err
+	}
+
+	// The Service is switched over to the promoted Deployment once the new
+	// state has been returned, and the status update made for it triggers
+	// another reconcile, which deletes the previously active Deployment
+	// once the scale down delay has passed.
+	log.Info("promoting preview Deployment")
+	r.Recorder.Eventf(myKind, core.EventTypeNormal, "BlueGreenPromoted", "Promoted deployment %q to active", colorDeploymentName(*myKind, previewColor))
+	if state.ActiveColor != "" {
+		scaleDownTime := metav1.NewTime(now.Add(strategy.ScaleDownDelay.Duration))
+		state.ScaleDownTime = &scaleDownTime
+	}
+	state.ActiveColor = previewColor
+	state.PreviewColor = ""
+	state.PreviewAvailable = false
+	observed.workload = previewWorkload
+	observed.previewDeployment = nil
+	if activeFound {
+		observed.previewDeployment = active
+	}
+
+	return observed, result, nil
+}
+
+// observeBlueGreen retrieves the state of the blue/green rollout of the
+// given MyKind from its status, its active Deployment and the Deployment of
+// the other colour, without modifying anything. The active Deployment is the
+// preview Deployment until there is an active one.
+func (r *MyKindReconciler) observeBlueGreen(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) (*mygroupv1.BlueGreenStatus, workloadObject, *apps.Deployment, error) {
+	state := &mygroupv1.BlueGreenStatus{}
+	if myKind.Status.BlueGreen != nil {
+		state = myKind.Status.BlueGreen.DeepCopy()
+	}
+
+	var workload workloadObject
+	var preview *apps.Deployment
+	for _, color := range []mygroupv1.DeploymentColor{state.ActiveColor, otherColor(state.ActiveColor)} {
+		if color == "" {
+			continue
+		}
+		deployment := &apps.Deployment{}
+		found, err := r.getControlledByName(ctx, myKind, colorDeploymentName(*myKind, color), deployment)
+		if err != nil {
+			log.Error(err, "failed to get Deployment for MyKind resource", "color", color)
+			return nil, nil, nil, err
+		}
+		if !found {
+			continue
+		}
+		if color == state.ActiveColor || state.ActiveColor == "" {
+			workload = deployment
+		} else {
+			preview = deployment
+		}
+	}
+
+	return state, workload, preview, nil
+}
+
+// blueGreenEnabled returns true if the workload of the given MyKind is
+// rolled out with blue and green Deployments.
+func blueGreenEnabled(myKind mygroupv1.MyKind) bool {
+	return myKind.Spec.Rollout != nil && myKind.Spec.Rollout.BlueGreen != nil &&
+		workloadKind(myKind) == mygroupv1.WorkloadKindDeployment
+}
+
+// colorDeploymentName returns the name of the Deployment of the given colour
+// of the given MyKind.
+func colorDeploymentName(myKind mygroupv1.MyKind, color mygroupv1.DeploymentColor) string {
+	return myKind.Spec.Deployment.Name + "-" + string(color)
+}
+
+// otherColor returns the colour of the Deployment that is not of the given
+// colour, which is blue if no colour is given.
+func otherColor(color mygroupv1.DeploymentColor) mygroupv1.DeploymentColor {
+	if color == mygroupv1.DeploymentColorBlue {
+		return mygroupv1.DeploymentColorGreen
+	}
+	return mygroupv1.DeploymentColorBlue
+}
+
+// buildColorDeployment renders the Deployment of the given colour for a
+// MyKind, given the Deployment built for it.
+// The pods have the labels of the Deployment built for the MyKind as well
+// as the ColorLabel, which is added to the selector so that the Deployments
+// of the two colours do not select each other's pods.
+func buildColorDeployment(desired apps.Deployment, color mygroupv1.DeploymentColor) *apps.Deployment {
+	deployment := desired.DeepCopy()
+	deployment.Name = desired.Name + "-" + string(color)
+	deployment.Labels[mygroupv1.ColorLabel] = string(color)
+	deployment.Spec.Selector.MatchLabels[mygroupv1.ColorLabel] = string(color)
+	deployment.Spec.Template.Labels[mygroupv1.ColorLabel] = string(color)
+	return deployment
+}
+
+// isColorWorkload returns true if the given workload was created for a
+// blue/green rollout of a MyKind.
+func isColorWorkload(workload workloadObject) bool {
+	_, ok := workload.GetLabels()[mygroupv1.ColorLabel]
+	return ok
+}
+
+// colorSelector returns the selector of the pods of the Deployment of the
+// given colour of the given MyKind, or of all of its pods if no colour is
+// given.
+func colorSelector(myKind mygroupv1.MyKind, color mygroupv1.DeploymentColor) map[string]string {
+	selector := deploymentLabels(myKind)
+	if color != "" {
+		selector[mygroupv1.ColorLabel] = string(color)
+	}
+	return selector
+}
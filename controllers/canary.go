@@ -0,0 +1,350 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/go-logr/logr"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// reconcileCanary ensures that the Deployment of the given MyKind, which is
+// rolled out with spec.rollout.canary, exists and is up to date.
+// A pod template that differs from that of the existing stable Deployment
+// is run in a canary Deployment, which is scaled according to the current
+// step of the rollout while the stable Deployment runs the rest of the
+// replicas. The state of the rollout is read from the status of the MyKind,
+// and the new state is returned for it to be written back.
+// desired is the Deployment built for the MyKind.
+// It returns the stable and canary Deployments as last observed or written
+// by the controller.
+func (r *MyKindReconciler) reconcileCanary(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, desired *apps.Deployment) (observedState, ctrl.Result, error) {
+	var observed observedState
+	var result ctrl.Result
+	strategy := myKind.Spec.Rollout.Canary
+	canaryName := canaryDeploymentName(*myKind)
+	log = log.WithValues("canary_deployment_name", canaryName)
+
+	stable := &apps.Deployment{}
+	found, err := r.getControlled(ctx, myKind, stable)
+	if err != nil {
+		log.Error(err, "failed to get stable Deployment for MyKind resource")
+		return observed, result, err
+	}
+	canary := &apps.Deployment{}
+	canaryFound, err := r.getControlledByName(ctx, myKind, canaryName, canary)
+	if err != nil {
+		log.Error(err, "failed to get canary Deployment for MyKind resource")
+		return observed, result, err
+	}
+
+	if !found || len(correctPodTemplateDrift(stable.Spec.Template.DeepCopy(), &desired.Spec.Template)) == 0 {
+		// A new Deployment has nothing to roll out from, and one that is
+		// not controlled by the MyKind is adopted as it is, so both are
+		// reconciled in the same way as without a rollout strategy.
+		log.Info("no canary rollout in progress")
+		observed.workload, result, err = r.reconcileWorkloadObject(ctx, log, myKind, desired)
+		if err != nil {
+			return observed, result, err
+		}
+		if err := r.clearPromotion(ctx, myKind); err != nil {
+			log.Error(err, "failed to remove promote annotation from MyKind resource")
+			return observed, result, err
+		}
+		if !canaryFound {
+			return observed, result, nil
+		}
+
+		// The canary Deployment keeps running until the stable Deployment
+		// has been promoted to its pod template and rolled out, so that
+		// the replicas remain available throughout.
+		if summary := summarizeWorkload(observed.workload); summary == nil || !summary.complete {
+			log.Info("waiting for stable Deployment to be rolled out before deleting canary Deployment")
+			observed.canary = newCanaryStatus(myKind, canaryName, hashPodTemplate(&desired.Spec.Template))
+			observed.canary.Phase = mygroupv1.CanaryPhasePromoting
+			observed.canary.CurrentStep = int32(len(strategy.Steps))
+			observed.canaryDeployment = canary
+			return observed, result, nil
+		}
+
+		return observed, result, r.deleteDeployment(ctx, log, myKind, canary)
+	}
+
+	observed.workload = stable
+	replicas := *desired.Spec.Replicas
+	hash := hashPodTemplate(&desired.Spec.Template)
+	state := newCanaryStatus(myKind, canaryName, hash)
+	observed.canary = state
+
+	if strategy.Abort {
+		log.Info("canary rollout aborted, running all replicas on the stable Deployment")
+		if state.Phase != mygroupv1.CanaryPhaseAborted {
+			r.Recorder.Eventf(myKind, core.EventTypeWarning, "CanaryAborted", "Aborted canary rollout of deployment %q", stable.Name)
+		}
+		state.Phase = mygroupv1.CanaryPhaseAborted
+		state.StepReadyTime = nil
+
+		if canaryFound {
+			if err := r.deleteDeployment(ctx, log, myKind, canary); err != nil {
+				return observed, result, err
+			}
+		}
+		if err := r.clearPromotion(ctx, myKind); err != nil {
+			log.Error(err, "failed to remove promote annotation from MyKind resource")
+			return observed, result, err
+		}
+		return observed, result, r.scaleDeployment(ctx, log, myKind, stable, replicas)
+	}
+
+	if state.Phase == mygroupv1.CanaryPhaseAborted {
+		// abort has been unset since the rollout was aborted, so it starts
+		// again from the first step.
+		state = &mygroupv1.CanaryStatus{DeploymentName: canaryName, TemplateHash: hash}
+		observed.canary = state
+	}
+	if state.Phase == "" {
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "CanaryStarted", "Started canary rollout of deployment %q", stable.Name)
+		state.Phase = mygroupv1.CanaryPhaseProgressing
+	}
+
+	if int(state.CurrentStep) >= len(strategy.Steps) {
+		log.Info("all canary steps are complete, promoting the new pod template to the stable Deployment")
+		observed.workload, result, err = r.reconcileWorkloadObject(ctx, log, myKind, desired)
+		if err != nil {
+			return observed, result, err
+		}
+		if canaryFound {
+			observed.canaryDeployment = canary
+		}
+		state.Phase = mygroupv1.CanaryPhasePromoting
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "CanaryPromoted", "Promoted canary of deployment %q", stable.Name)
+		return observed, result, nil
+	}
+
+	step := strategy.Steps[state.CurrentStep]
+	canaryReplicas := canaryStepReplicas(step, replicas)
+	log = log.WithValues("step", state.CurrentStep, "canary_replicas", canaryReplicas)
+
+	canaryWorkload, result, err := r.reconcileWorkloadObject(ctx, log, myKind, buildCanaryDeployment(*desired, canaryName, canaryReplicas))
+	if canaryWorkload != nil {
+		observed.canaryDeployment = canaryWorkload.(*apps.Deployment)
+	}
+	if err != nil {
+		return observed, result, err
+	}
+	if err := r.scaleDeployment(ctx, log, myKind, stable, replicas-canaryReplicas); err != nil {
+		return observed, result, err
+	}
+
+	if summary := summarizeWorkload(canaryWorkload); summary == nil || !summary.complete {
+		log.Info("waiting for canary replicas to become available")
+		state.Phase = mygroupv1.CanaryPhaseProgressing
+		state.StepReadyTime = nil
+		return observed, result, nil
+	}
+
+	now := metav1.NewTime(r.now())
+	if state.StepReadyTime == nil {
+		state.StepReadyTime = &now
+	}
+
+	if myKind.Annotations[mygroupv1.PromoteAnnotation] == "true" {
+		log.Info("canary step promoted")
+		if err := r.clearPromotion(ctx, myKind); err != nil {
+			log.Error(err, "failed to remove promote annotation from MyKind resource")
+			return observed, result, err
+		}
+	} else {
+		if step.Pause != nil {
+			if remaining := state.StepReadyTime.Add(step.Pause.Duration).Sub(now.Time); remaining > 0 {
+				log.Info("canary step is paused", "remaining", remaining)
+				state.Phase = mygroupv1.CanaryPhasePaused
+				result.RequeueAfter = remaining
+				return observed, result, nil
+			}
+		}
+		if step.ManualPromotion {
+			log.Info("canary step is awaiting promotion")
+			state.Phase = mygroupv1.CanaryPhaseAwaitingPromotion
+			return observed, result, nil
+		}
+	}
+
+	// The status update made for the new step triggers another reconcile,
+	// which moves on to it.
+	r.Recorder.Eventf(myKind, core.EventTypeNormal, "CanaryStepCompleted", "Completed step %d of %d of canary rollout of deployment %q", state.CurrentStep+1, len(strategy.Steps), stable.Name)
+	state.CurrentStep++
+	state.StepReadyTime = nil
+	state.Phase = mygroupv1.CanaryPhaseProgressing
+
+	return observed, result, nil
+}
+
+// observeCanary retrieves the state of the canary rollout of the given
+// MyKind from its status, and its canary Deployment, without modifying
+// anything.
+func (r *MyKindReconciler) observeCanary(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) (*mygroupv1.CanaryStatus, *apps.Deployment, error) {
+	if !canaryEnabled(*myKind) || myKind.Status.Canary == nil {
+		return nil, nil, nil
+	}
+
+	canary := &apps.Deployment{}
+	found, err := r.getControlledByName(ctx, myKind, canaryDeploymentName(*myKind), canary)
+	if err != nil {
+		log.Error(err, "failed to get canary Deployment for MyKind resource")
+		return nil, nil, err
+	}
+	if !found {
+		canary = nil
+	}
+
+	return myKind.Status.Canary.DeepCopy(), canary, nil
+}
+
+// deleteDeployment deletes the given Deployment created for a rollout of
+// the given MyKind.
+func (r *MyKindReconciler) deleteDeployment(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, deployment *apps.Deployment) error {
+	log.Info("deleting Deployment", "name", deployment.Name)
+	if err := r.Client.Delete(ctx, deployment); client.IgnoreNotFound(err) != nil {
+		log.Error(err, "failed to delete Deployment", "name", deployment.Name)
+		return err
+	}
+
+	r.Recorder.Eventf(myKind, core.EventTypeNormal, "Deleted", "Deleted deployment %q", deployment.Name)
+	return nil
+}
+
+// scaleDeployment sets the replica count of the given Deployment, leaving
+// the rest of it untouched.
+func (r *MyKindReconciler) scaleDeployment(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, deployment *apps.Deployment, replicas int32) error {
+	if deploymentReplicas(deployment) == replicas {
+		return nil
+	}
+
+	log.Info("updating replica count of Deployment", "name", deployment.Name, "old_count", deploymentReplicas(deployment), "new_count", replicas)
+	base := deployment.DeepCopy()
+	deployment.Spec.Replicas = &replicas
+	if err := r.patch(ctx, deployment, base); err != nil {
+		log.Error(err, "failed to scale Deployment", "name", deployment.Name)
+		return err
+	}
+
+	r.Recorder.Eventf(myKind, core.EventTypeNormal, "Scaled", "Scaled deployment %q to %d replicas", deployment.Name, replicas)
+	return nil
+}
+
+// clearPromotion removes the PromoteAnnotation from the given MyKind, if it
+// is set, so that it is only acted upon once.
+func (r *MyKindReconciler) clearPromotion(ctx context.Context, myKind *mygroupv1.MyKind) error {
+	if _, ok := myKind.Annotations[mygroupv1.PromoteAnnotation]; !ok {
+		return nil
+	}
+
+	// The MyKind has had defaults applied that are not persisted, so the
+	// patch is made to a copy and only the metadata is copied back.
+	patched := myKind.DeepCopy()
+	delete(patched.Annotations, mygroupv1.PromoteAnnotation)
+	if err := r.patch(ctx, patched, myKind); err != nil {
+		return err
+	}
+
+	myKind.Annotations = patched.Annotations
+	myKind.ResourceVersion = patched.ResourceVersion
+	return nil
+}
+
+// newCanaryStatus returns the state of the canary rollout of the pod
+// template with the given hash, continuing from the state in the status of
+// the given MyKind if it is of a rollout of the same pod template.
+func newCanaryStatus(myKind *mygroupv1.MyKind, deploymentName, templateHash string) *mygroupv1.CanaryStatus {
+	if current := myKind.Status.Canary; current != nil && current.TemplateHash == templateHash {
+		state := current.DeepCopy()
+		state.DeploymentName = deploymentName
+		return state
+	}
+	return &mygroupv1.CanaryStatus{
+		DeploymentName: deploymentName,
+		TemplateHash:   templateHash,
+	}
+}
+
+// canaryEnabled returns true if the workload of the given MyKind is rolled
+// out with a canary Deployment.
+func canaryEnabled(myKind mygroupv1.MyKind) bool {
+	return myKind.Spec.Rollout != nil && myKind.Spec.Rollout.Canary != nil &&
+		workloadKind(myKind) == mygroupv1.WorkloadKindDeployment
+}
+
+// canaryDeploymentName returns the name of the canary Deployment of the
+// given MyKind.
+func canaryDeploymentName(myKind mygroupv1.MyKind) string {
+	return myKind.Spec.Deployment.Name + mygroupv1.CanaryDeploymentSuffix
+}
+
+// canaryStepReplicas returns the number of the given replicas that are run
+// by the canary Deployment during the given step.
+func canaryStepReplicas(step mygroupv1.CanaryStep, replicas int32) int32 {
+	canaryReplicas := replicas
+	switch {
+	case step.Weight != nil:
+		canaryReplicas = (replicas**step.Weight + 99) / 100
+	case step.Replicas != nil:
+		canaryReplicas = *step.Replicas
+	}
+	if canaryReplicas > replicas {
+		return replicas
+	}
+	return canaryReplicas
+}
+
+// buildCanaryDeployment renders the canary Deployment for a MyKind, given
+// the Deployment built for it, with the given number of replicas.
+// The canary pods have the labels of the stable pods as well as the
+// CanaryLabel, which is added to the selector so that the canary Deployment
+// does not select the stable pods.
+func buildCanaryDeployment(desired apps.Deployment, name string, replicas int32) *apps.Deployment {
+	canary := desired.DeepCopy()
+	canary.Name = name
+	canary.Labels[mygroupv1.CanaryLabel] = "true"
+	canary.Spec.Replicas = &replicas
+	canary.Spec.Selector.MatchLabels[mygroupv1.CanaryLabel] = "true"
+	canary.Spec.Template.Labels[mygroupv1.CanaryLabel] = "true"
+	return canary
+}
+
+// hashPodTemplate returns a short hash that identifies the given pod
+// template.
+func hashPodTemplate(template *core.PodTemplateSpec) string {
+	// The encoding of a pod template is stable, as maps are encoded with
+	// their keys sorted.
+	data, err := json.Marshal(template)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:10]
+}
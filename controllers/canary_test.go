@@ -0,0 +1,49 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	"k8s.io/utils/pointer"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+func TestCanaryStepReplicas(t *testing.T) {
+	tests := []struct {
+		name     string
+		step     mygroupv1.CanaryStep
+		replicas int32
+		want     int32
+	}{
+		{name: "weight", step: mygroupv1.CanaryStep{Weight: pointer.Int32Ptr(50)}, replicas: 4, want: 2},
+		{name: "weight rounded up", step: mygroupv1.CanaryStep{Weight: pointer.Int32Ptr(10)}, replicas: 4, want: 1},
+		{name: "zero weight", step: mygroupv1.CanaryStep{Weight: pointer.Int32Ptr(0)}, replicas: 4, want: 0},
+		{name: "full weight", step: mygroupv1.CanaryStep{Weight: pointer.Int32Ptr(100)}, replicas: 4, want: 4},
+		{name: "replicas", step: mygroupv1.CanaryStep{Replicas: pointer.Int32Ptr(3)}, replicas: 4, want: 3},
+		{name: "replicas above the total", step: mygroupv1.CanaryStep{Replicas: pointer.Int32Ptr(6)}, replicas: 4, want: 4},
+		{name: "no replicas", step: mygroupv1.CanaryStep{Weight: pointer.Int32Ptr(50)}, replicas: 0, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canaryStepReplicas(tt.step, tt.replicas); got != tt.want {
+				t.Errorf("expected %d canary replicas, got %d", tt.want, got)
+			}
+		})
+	}
+}
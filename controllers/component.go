@@ -0,0 +1,110 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	apps "k8s.io/api/apps/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// reconcileComponents ensures that the Deployment of each component of the
+// given MyKind exists and is up to date.
+// It returns the Deployments as last observed or written by the controller,
+// in the order of spec.components. Each will be nil if it could not be
+// retrieved or created.
+func (r *MyKindReconciler) reconcileComponents(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) ([]workloadObject, ctrl.Result, error) {
+	var result ctrl.Result
+	workloads := make([]workloadObject, len(myKind.Spec.Components))
+
+	for i, component := range myKind.Spec.Components {
+		log := log.WithValues("component", component.Name, "deployment_name", component.Deployment.Name)
+		componentKind := componentMyKind(*myKind, component)
+
+		configHash, err := r.computeConfigHash(ctx, componentKind)
+		if err != nil {
+			log.Error(err, "failed to compute hash of referenced ConfigMaps and Secrets")
+			return workloads, result, err
+		}
+
+		var componentResult ctrl.Result
+		workloads[i], componentResult, err = r.reconcileWorkloadObject(ctx, log, componentKind, buildComponentDeployment(*componentKind, component.Name, configHash))
+		result.Requeue = result.Requeue || componentResult.Requeue
+		if err != nil {
+			return workloads, result, err
+		}
+	}
+
+	return workloads, result, nil
+}
+
+// observeComponents retrieves the Deployment of each component of the given
+// MyKind, in the order of spec.components. Each will be nil if it does not
+// exist or is not controlled by the MyKind.
+func (r *MyKindReconciler) observeComponents(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) ([]workloadObject, error) {
+	workloads := make([]workloadObject, len(myKind.Spec.Components))
+
+	for i, component := range myKind.Spec.Components {
+		deployment := &apps.Deployment{}
+		found, err := r.getControlled(ctx, componentMyKind(*myKind, component), deployment)
+		if err != nil {
+			log.Error(err, "failed to get Deployment for component", "component", component.Name)
+			return workloads, err
+		}
+		if found {
+			workloads[i] = deployment
+		}
+	}
+
+	return workloads, nil
+}
+
+// componentMyKind returns a copy of the given MyKind that describes the
+// given component in place of spec.deployment, so that the Deployment of the
+// component can be rendered and reconciled in the same way as the workload
+// of the MyKind itself.
+// Events recorded for the copy are recorded for the MyKind.
+func componentMyKind(myKind mygroupv1.MyKind, component mygroupv1.ComponentSpec) *mygroupv1.MyKind {
+	componentKind := myKind.DeepCopy()
+	componentKind.Spec.Deployment = *component.Deployment.DeepCopy()
+	componentKind.Spec.WorkloadKind = mygroupv1.WorkloadKindDeployment
+	componentKind.Spec.Components = nil
+	componentKind.Spec.Autoscaling = nil
+	return componentKind
+}
+
+// buildComponentDeployment renders the Deployment for a component of a
+// MyKind, given the MyKind returned for it by componentMyKind.
+// The Deployment and its pods are labelled with the name of the component,
+// which is not part of the Deployment's selector.
+func buildComponentDeployment(componentKind mygroupv1.MyKind, name, configHash string) *apps.Deployment {
+	deployment := buildDeployment(componentKind, configHash)
+	deployment.Labels[mygroupv1.ComponentLabel] = name
+	deployment.Spec.Template.Labels[mygroupv1.ComponentLabel] = name
+	return deployment
+}
+
+// isComponentWorkload returns true if the given workload was created for a
+// component of a MyKind.
+func isComponentWorkload(workload workloadObject) bool {
+	_, ok := workload.GetLabels()[mygroupv1.ComponentLabel]
+	return ok
+}
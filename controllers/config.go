@@ -0,0 +1,203 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// configHashAnnotation is set on the pod template of the Deployment created
+// for a MyKind to a hash of the ConfigMaps and Secrets it references, so
+// that changing any of them triggers a rollout.
+const configHashAnnotation = "example-controller.jetstack.io/config-hash"
+
+// configVolumeMode is the mode that the files of ConfigMap and Secret
+// volumes are created with. It is the same as the apiserver's default, and
+// is set explicitly so that volumes can be compared when correcting drift.
+const configVolumeMode = int32(0644)
+
+var (
+	configMapRefKey = ".spec.deployment.configMaps"
+	secretRefKey    = ".spec.deployment.secrets"
+)
+
+// configContents is the representation of a ConfigMap or Secret that is
+// hashed to compute the value of the configHashAnnotation.
+// The contents of Secrets are not hashed, as the annotation is readable by
+// anyone who can read the Deployment and an unkeyed hash of a low entropy
+// secret can be brute forced. Secrets are identified by their UID and
+// resourceVersion instead, which change whenever the Secret is recreated or
+// modified.
+type configContents struct {
+	Kind            string            `json:"kind"`
+	Name            string            `json:"name"`
+	Data            map[string]string `json:"data,omitempty"`
+	BinaryData      map[string][]byte `json:"binaryData,omitempty"`
+	UID             string            `json:"uid,omitempty"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+}
+
+// computeConfigHash returns a hash of the ConfigMaps and Secrets referenced
+// by the given MyKind, or an empty string if it does not reference any.
+func (r *MyKindReconciler) computeConfigHash(ctx context.Context, myKind *mygroupv1.MyKind) (string, error) {
+	spec := myKind.Spec.Deployment
+	if len(spec.ConfigMaps) == 0 && len(spec.Secrets) == 0 {
+		return "", nil
+	}
+
+	var contents []configContents
+	for _, ref := range spec.ConfigMaps {
+		cm := core.ConfigMap{}
+		if err := r.Client.Get(ctx, client.ObjectKey{Namespace: myKind.Namespace, Name: ref.Name}, &cm); err != nil {
+			return "", fmt.Errorf("failed to get config map %q: %v", ref.Name, err)
+		}
+		contents = append(contents, configContents{Kind: "ConfigMap", Name: cm.Name, Data: cm.Data, BinaryData: cm.BinaryData})
+	}
+	for _, ref := range spec.Secrets {
+		secret := core.Secret{}
+		if err := r.Client.Get(ctx, client.ObjectKey{Namespace: myKind.Namespace, Name: ref.Name}, &secret); err != nil {
+			return "", fmt.Errorf("failed to get secret %q: %v", ref.Name, err)
+		}
+		contents = append(contents, configContents{Kind: "Secret", Name: secret.Name, UID: string(secret.UID), ResourceVersion: secret.ResourceVersion})
+	}
+
+	// Maps are encoded with their keys sorted, so the encoding is stable.
+	data, err := json.Marshal(contents)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// buildConfigVolumes renders the volumes, volume mounts and environment
+// sources that make the ConfigMaps and Secrets referenced by the given
+// MyKind available to its container.
+func buildConfigVolumes(myKind mygroupv1.MyKind) ([]core.Volume, []core.VolumeMount, []core.EnvFromSource) {
+	var volumes []core.Volume
+	var mounts []core.VolumeMount
+	var envFrom []core.EnvFromSource
+
+	for i, ref := range myKind.Spec.Deployment.ConfigMaps {
+		if ref.MountPath == "" {
+			envFrom = append(envFrom, core.EnvFromSource{
+				ConfigMapRef: &core.ConfigMapEnvSource{LocalObjectReference: core.LocalObjectReference{Name: ref.Name}},
+			})
+			continue
+		}
+
+		// Volume names must be DNS labels, which the name of the ConfigMap
+		// may not be, so volumes are named after their position instead.
+		name := "configmap-" + strconv.Itoa(i)
+		volumes = append(volumes, core.Volume{
+			Name: name,
+			VolumeSource: core.VolumeSource{
+				ConfigMap: &core.ConfigMapVolumeSource{
+					LocalObjectReference: core.LocalObjectReference{Name: ref.Name},
+					DefaultMode:          pointer.Int32Ptr(configVolumeMode),
+				},
+			},
+		})
+		mounts = append(mounts, core.VolumeMount{Name: name, MountPath: ref.MountPath, ReadOnly: true})
+	}
+
+	for i, ref := range myKind.Spec.Deployment.Secrets {
+		if ref.MountPath == "" {
+			envFrom = append(envFrom, core.EnvFromSource{
+				SecretRef: &core.SecretEnvSource{LocalObjectReference: core.LocalObjectReference{Name: ref.Name}},
+			})
+			continue
+		}
+
+		name := "secret-" + strconv.Itoa(i)
+		volumes = append(volumes, core.Volume{
+			Name: name,
+			VolumeSource: core.VolumeSource{
+				Secret: &core.SecretVolumeSource{
+					SecretName:  ref.Name,
+					DefaultMode: pointer.Int32Ptr(configVolumeMode),
+				},
+			},
+		})
+		mounts = append(mounts, core.VolumeMount{Name: name, MountPath: ref.MountPath, ReadOnly: true})
+	}
+
+	return volumes, mounts, envFrom
+}
+
+// indexConfigMapReferences returns the names of the ConfigMaps referenced by
+// the given MyKind and its components, for use as a field index.
+func indexConfigMapReferences(rawObj runtime.Object) []string {
+	myKind := rawObj.(*mygroupv1.MyKind)
+	names := configReferenceNames(myKind.Spec.Deployment.ConfigMaps)
+	for _, component := range myKind.Spec.Components {
+		names = append(names, configReferenceNames(component.Deployment.ConfigMaps)...)
+	}
+	return names
+}
+
+// indexSecretReferences returns the names of the Secrets referenced by the
+// given MyKind and its components, for use as a field index.
+func indexSecretReferences(rawObj runtime.Object) []string {
+	myKind := rawObj.(*mygroupv1.MyKind)
+	names := configReferenceNames(myKind.Spec.Deployment.Secrets)
+	for _, component := range myKind.Spec.Components {
+		names = append(names, configReferenceNames(component.Deployment.Secrets)...)
+	}
+	return names
+}
+
+func configReferenceNames(refs []mygroupv1.ConfigReference) []string {
+	var names []string
+	for _, ref := range refs {
+		names = append(names, ref.Name)
+	}
+	return names
+}
+
+// myKindsReferencing returns a function that maps a ConfigMap or Secret to
+// requests for each of the MyKinds in its namespace that reference it,
+// according to the MyKind field index with the given key.
+func (r *MyKindReconciler) myKindsReferencing(key string) handler.ToRequestsFunc {
+	return func(obj handler.MapObject) []ctrl.Request {
+		var myKinds mygroupv1.MyKindList
+		if err := r.List(context.Background(), &myKinds, client.InNamespace(obj.Meta.GetNamespace()), client.MatchingField(key, obj.Meta.GetName())); err != nil {
+			r.Log.Error(err, "failed to list MyKind resources referencing object", "namespace", obj.Meta.GetNamespace(), "name", obj.Meta.GetName())
+			return nil
+		}
+
+		var requests []ctrl.Request
+		for _, myKind := range myKinds.Items {
+			requests = append(requests, ctrl.Request{NamespacedName: client.ObjectKey{Namespace: myKind.Namespace, Name: myKind.Name}})
+		}
+		return requests
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1beta1"
+	policy "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// myKindFinalizer is added to every MyKind resource by the controller, so
+// that its spec.deletionPolicy can be enforced before it is removed.
+const myKindFinalizer = "example-controller.jetstack.io/deletion-policy"
+
+// ensureFinalizer adds the controller's finalizer to the given MyKind if it
+// is not already present.
+func (r *MyKindReconciler) ensureFinalizer(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) error {
+	if hasFinalizer(myKind.Finalizers, myKindFinalizer) {
+		return nil
+	}
+
+	log.Info("adding finalizer to MyKind resource")
+	myKind.Finalizers = append(myKind.Finalizers, myKindFinalizer)
+	return r.Client.Update(ctx, myKind)
+}
+
+// finalize applies the deletion policy of the given MyKind to its
+// workloads and the other resources created for it, and then removes the
+// controller's finalizer so that the MyKind can be deleted.
+// The policy is applied to the workloads of every kind, as a workload of a
+// previous spec.workloadKind is still running pods until it is replaced.
+// Resources that are not controlled by the MyKind are never modified.
+func (r *MyKindReconciler) finalize(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) error {
+	if !hasFinalizer(myKind.Finalizers, myKindFinalizer) {
+		return nil
+	}
+
+	log = log.WithValues("deployment_name", myKind.Spec.Deployment.Name, "deletion_policy", myKind.Spec.DeletionPolicy)
+
+	workloads, err := r.listOwnedWorkloads(ctx, myKind, workloadKinds)
+	if err != nil {
+		log.Error(err, "failed to list workloads for MyKind resource")
+		return err
+	}
+	if len(workloads) == 0 {
+		log.Info("no workload exists for MyKind, nothing to clean up")
+	}
+	for _, workload := range workloads {
+		if !metav1.IsControlledBy(workload, myKind) {
+			continue
+		}
+		if err := r.applyDeletionPolicy(ctx, log.WithValues("workload_kind", kindOf(workload), "workload_name", workload.GetName()), myKind, workload); err != nil {
+			return err
+		}
+	}
+
+	if myKind.Spec.DeletionPolicy == mygroupv1.DeletionPolicyOrphan || myKind.Spec.DeletionPolicy == mygroupv1.DeletionPolicyScaleToZero {
+		// Orphaned pods should remain reachable and protected, so the
+		// resources in front of them are orphaned along with the workload
+		// rather than being garbage collected.
+		if err := r.orphanResource(ctx, log, myKind, &core.Service{}, "service"); err != nil {
+			return err
+		}
+		if err := r.orphanResource(ctx, log, myKind, &networking.Ingress{}, "ingress"); err != nil {
+			return err
+		}
+		if err := r.orphanResource(ctx, log, myKind, &policy.PodDisruptionBudget{}, "pod disruption budget"); err != nil {
+			return err
+		}
+		if err := r.orphanResource(ctx, log, myKind, &autoscalingv2beta2.HorizontalPodAutoscaler{}, "horizontal pod autoscaler"); err != nil {
+			return err
+		}
+	}
+
+	log.Info("removing finalizer from MyKind resource")
+	myKind.Finalizers = removeFinalizer(myKind.Finalizers, myKindFinalizer)
+	if err := r.Client.Update(ctx, myKind); err != nil {
+		log.Error(err, "failed to remove finalizer from MyKind resource")
+		return err
+	}
+
+	return nil
+}
+
+// applyDeletionPolicy deletes, orphans or scales down the given workload
+// according to the deletion policy of the MyKind that controls it.
+func (r *MyKindReconciler) applyDeletionPolicy(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, workload workloadObject) error {
+	kind := kindOf(workload)
+	base := workload.DeepCopyObject()
+
+	switch myKind.Spec.DeletionPolicy {
+	case mygroupv1.DeletionPolicyOrphan:
+		log.Info("orphaning workload")
+
+		workload.SetOwnerReferences(removeOwnerReference(workload.GetOwnerReferences(), myKind))
+		if err := r.patch(ctx, workload, base); err != nil {
+			log.Error(err, "failed to orphan workload")
+			return err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Orphaned", "Orphaned %s %q", workloadKindName(kind), workload.GetName())
+
+	case mygroupv1.DeletionPolicyScaleToZero:
+		log.Info("scaling workload to zero replicas and orphaning it")
+
+		// DaemonSets cannot be scaled, so a DaemonSet of a previous
+		// spec.workloadKind is only orphaned. The policy is rejected by
+		// validation while spec.workloadKind is DaemonSet.
+		if replicas := workloadReplicas(workload); replicas != nil {
+			*replicas = 0
+		}
+		workload.SetOwnerReferences(removeOwnerReference(workload.GetOwnerReferences(), myKind))
+		if err := r.patch(ctx, workload, base); err != nil {
+			log.Error(err, "failed to scale workload to zero replicas")
+			return err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "ScaledToZero", "Scaled %s %q to 0 replicas and orphaned it", workloadKindName(kind), workload.GetName())
+
+	default:
+		// The workload would be garbage collected once the MyKind is gone,
+		// but deleting it here means that it is gone by the time the MyKind
+		// is.
+		log.Info("deleting workload")
+
+		if err := r.Client.Delete(ctx, workload); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "failed to delete workload")
+			return err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Deleted", "Deleted %s %q", workloadKindName(kind), workload.GetName())
+	}
+
+	return nil
+}
+
+// orphanResource removes the owner reference of the given MyKind from the
+// resource of obj's type named after its workload, so that it is not
+// garbage collected when the MyKind is deleted. kind is used in events.
+func (r *MyKindReconciler) orphanResource(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, obj runtime.Object, kind string) error {
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: myKind.Namespace, Name: myKind.Spec.Deployment.Name}, obj)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		log.Error(err, "failed to get resource for MyKind", "kind", kind)
+		return err
+	}
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(accessor, myKind) {
+		return nil
+	}
+
+	log.Info("orphaning resource", "kind", kind, "name", accessor.GetName())
+
+	base := obj.DeepCopyObject()
+	accessor.SetOwnerReferences(removeOwnerReference(accessor.GetOwnerReferences(), myKind))
+	if err := r.patch(ctx, obj, base); err != nil {
+		log.Error(err, "failed to orphan resource", "kind", kind)
+		return err
+	}
+
+	r.Recorder.Eventf(myKind, core.EventTypeNormal, "Orphaned", "Orphaned %s %q", kind, accessor.GetName())
+
+	return nil
+}
+
+func hasFinalizer(finalizers []string, finalizer string) bool {
+	for _, f := range finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+func removeFinalizer(finalizers []string, finalizer string) []string {
+	var result []string
+	for _, f := range finalizers {
+		if f != finalizer {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// removeOwnerReference returns the given owner references without any that
+// refer to owner.
+func removeOwnerReference(refs []metav1.OwnerReference, owner metav1.Object) []metav1.OwnerReference {
+	var result []metav1.OwnerReference
+	for _, ref := range refs {
+		if ref.UID != owner.GetUID() {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
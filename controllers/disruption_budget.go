@@ -0,0 +1,200 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// reconcilePodDisruptionBudget ensures that the PodDisruptionBudget for the
+// given MyKind exists and is up to date if spec.disruptionBudget is set and
+// the Deployment has at least one replica, and cleans up any
+// PodDisruptionBudgets that are no longer needed.
+// replicas is the number of replicas the Deployment is currently scaled to.
+func (r *MyKindReconciler) reconcilePodDisruptionBudget(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, replicas int32) (ctrl.Result, error) {
+	wanted := myKind.Spec.DisruptionBudget != nil && replicas > 0
+
+	if err := r.cleanupOwnedPodDisruptionBudgets(ctx, log, myKind, wanted); err != nil {
+		log.Error(err, "failed to clean up old PodDisruptionBudget resources for this MyKind")
+		return ctrl.Result{}, err
+	}
+
+	if !wanted {
+		return ctrl.Result{}, nil
+	}
+
+	log = log.WithValues("pdb_name", myKind.Spec.Deployment.Name)
+
+	desired, relaxed, err := buildPodDisruptionBudget(*myKind, replicas)
+	if err != nil {
+		log.Error(err, "failed to build PodDisruptionBudget")
+		return ctrl.Result{}, err
+	}
+	if relaxed {
+		log.Info("disruption budget would never allow a pod to be evicted, relaxing it to allow one unavailable pod", "replicas", replicas)
+	}
+
+	log.Info("checking if an existing PodDisruptionBudget exists for this resource")
+	pdb := policy.PodDisruptionBudget{}
+	err = r.Client.Get(ctx, client.ObjectKey{Namespace: myKind.Namespace, Name: myKind.Spec.Deployment.Name}, &pdb)
+	if apierrors.IsNotFound(err) {
+		log.Info("could not find existing PodDisruptionBudget for MyKind, creating one...")
+
+		if err := r.apply(ctx, desired); err != nil {
+			log.Error(err, "failed to create PodDisruptionBudget resource")
+			return ctrl.Result{}, err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Created", "Created pod disruption budget %q", desired.Name)
+		log.Info("created PodDisruptionBudget resource for MyKind")
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		log.Error(err, "failed to get PodDisruptionBudget for MyKind resource")
+		return ctrl.Result{}, err
+	}
+
+	if !metav1.IsControlledBy(&pdb, myKind) {
+		return ctrl.Result{}, fmt.Errorf("pod disruption budget %q already exists and is not controlled by this MyKind", pdb.Name)
+	}
+
+	if !apiequality.Semantic.DeepEqual(pdb.Spec, desired.Spec) {
+		// The spec of a PodDisruptionBudget cannot be updated in all
+		// supported versions of Kubernetes, so it is deleted and created
+		// again instead.
+		log.Info("PodDisruptionBudget is out of date, deleting it so that it is recreated")
+
+		if err := r.Client.Delete(ctx, &pdb); err != nil {
+			log.Error(err, "failed to delete PodDisruptionBudget")
+			return ctrl.Result{}, err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Deleted", "Deleted pod disruption budget %q to update its spec", pdb.Name)
+
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	log.Info("PodDisruptionBudget up to date")
+
+	return ctrl.Result{}, nil
+}
+
+// cleanupOwnedPodDisruptionBudgets will Delete any existing
+// PodDisruptionBudget resources that were created for the given MyKind that
+// no longer match the myKind.spec.deployment.name field, or all of them if
+// wanted is false.
+func (r *MyKindReconciler) cleanupOwnedPodDisruptionBudgets(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, wanted bool) error {
+	log.Info("finding existing PodDisruptionBudgets for MyKind resource")
+
+	// List all pod disruption budget resources owned by this MyKind
+	var pdbs policy.PodDisruptionBudgetList
+	if err := r.List(ctx, &pdbs, client.InNamespace(myKind.Namespace), client.MatchingField(pdbOwnerKey, myKind.Name)); err != nil {
+		return err
+	}
+
+	deleted := 0
+	for _, pdb := range pdbs.Items {
+		if wanted && pdb.Name == myKind.Spec.Deployment.Name {
+			// If this pod disruption budget's name matches the one on the
+			// MyKind resource then do not delete it.
+			continue
+		}
+
+		if err := r.Client.Delete(ctx, &pdb); err != nil {
+			log.Error(err, "failed to delete PodDisruptionBudget resource")
+			return err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Deleted", "Deleted pod disruption budget %q", pdb.Name)
+		deleted++
+	}
+
+	log.Info("finished cleaning up old PodDisruptionBudget resources", "number_deleted", deleted)
+
+	return nil
+}
+
+// buildPodDisruptionBudget renders the PodDisruptionBudget for the given
+// MyKind, which must have spec.disruptionBudget set, for a Deployment with
+// the given number of replicas.
+// If the budget in the spec would not allow any pod to be evicted, a budget
+// allowing one unavailable pod is rendered instead and relaxed is true.
+func buildPodDisruptionBudget(myKind mygroupv1.MyKind, replicas int32) (pdb *policy.PodDisruptionBudget, relaxed bool, err error) {
+	budget := myKind.Spec.DisruptionBudget.DeepCopy()
+
+	allowed, err := allowedDisruptions(budget, replicas)
+	if err != nil {
+		return nil, false, err
+	}
+	if allowed < 1 {
+		maxUnavailable := intstr.FromInt(1)
+		budget = &mygroupv1.DisruptionBudgetSpec{MaxUnavailable: &maxUnavailable}
+		relaxed = true
+	}
+
+	pdb = &policy.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: policy.SchemeGroupVersion.String(),
+			Kind:       "PodDisruptionBudget",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            myKind.Spec.Deployment.Name,
+			Namespace:       myKind.Namespace,
+			Labels:          deploymentLabels(myKind),
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(&myKind, mygroupv1.GroupVersion.WithKind("MyKind"))},
+		},
+		Spec: policy.PodDisruptionBudgetSpec{
+			MinAvailable:   budget.MinAvailable,
+			MaxUnavailable: budget.MaxUnavailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: deploymentLabels(myKind),
+			},
+		},
+	}
+	return pdb, relaxed, nil
+}
+
+// allowedDisruptions returns the number of pods that the given budget
+// allows to be unavailable when all of the given number of replicas are
+// healthy, rounding percentages in the same way as the disruption
+// controller.
+func allowedDisruptions(budget *mygroupv1.DisruptionBudgetSpec, replicas int32) (int, error) {
+	if budget.MaxUnavailable != nil {
+		return intstr.GetValueFromIntOrPercent(budget.MaxUnavailable, int(replicas), true)
+	}
+	if budget.MinAvailable != nil {
+		minAvailable, err := intstr.GetValueFromIntOrPercent(budget.MinAvailable, int(replicas), true)
+		if err != nil {
+			return 0, err
+		}
+		return int(replicas) - minAvailable, nil
+	}
+	return int(replicas), nil
+}
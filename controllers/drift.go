@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	core "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+)
+
+// correctWorkloadDrift compares the fields of an existing workload that are
+// owned by the controller against the desired workload of the same kind
+// built for a MyKind, and copies the desired values onto existing wherever
+// they differ.
+// Fields that are not set by the controller, such as labels and annotations
+// added by other actors, are left untouched.
+// The replica count is not considered here as it may legitimately be
+// managed by another actor, and the selector is not considered as it is
+// immutable.
+// It returns the path of each field that was corrected.
+func correctWorkloadDrift(existing, desired workloadObject) []string {
+	var corrected []string
+
+	if !labelsUpToDate(existing.GetLabels(), desired.GetLabels()) {
+		existing.SetLabels(mergeLabels(existing.GetLabels(), desired.GetLabels()))
+		corrected = append(corrected, "metadata.labels")
+	}
+
+	return append(corrected, correctPodTemplateDrift(workloadTemplate(existing), workloadTemplate(desired))...)
+}
+
+// correctPodTemplateDrift compares the fields of the pod template of an
+// existing workload that are owned by the controller against the desired
+// pod template, and copies the desired values onto existing wherever they
+// differ.
+// It returns the path of each field that was corrected.
+func correctPodTemplateDrift(existing, desired *core.PodTemplateSpec) []string {
+	var corrected []string
+
+	if !labelsUpToDate(existing.Labels, desired.Labels) {
+		existing.Labels = mergeLabels(existing.Labels, desired.Labels)
+		corrected = append(corrected, "spec.template.metadata.labels")
+	}
+
+	if !configHashUpToDate(existing.Annotations, desired.Annotations) {
+		existing.Annotations = mergeLabels(existing.Annotations, desired.Annotations)
+		if _, ok := desired.Annotations[configHashAnnotation]; !ok {
+			delete(existing.Annotations, configHashAnnotation)
+		}
+		corrected = append(corrected, "spec.template.metadata.annotations")
+	}
+
+	if !containersUpToDate(existing.Spec.Containers, desired.Spec.Containers) {
+		existing.Spec.Containers = desired.Spec.Containers
+		corrected = append(corrected, "spec.template.spec.containers")
+	}
+
+	if !apiequality.Semantic.DeepEqual(existing.Spec.Volumes, desired.Spec.Volumes) {
+		existing.Spec.Volumes = desired.Spec.Volumes
+		corrected = append(corrected, "spec.template.spec.volumes")
+	}
+
+	return corrected
+}
+
+// labelsUpToDate returns true if every label in expected is set to the same
+// value in existing.
+func labelsUpToDate(existing, expected map[string]string) bool {
+	for k, v := range expected {
+		if existingV, ok := existing[k]; !ok || existingV != v {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeLabels returns a copy of existing with all of the labels in expected
+// set on it.
+func mergeLabels(existing, expected map[string]string) map[string]string {
+	merged := make(map[string]string, len(existing)+len(expected))
+	for k, v := range existing {
+		merged[k] = v
+	}
+	for k, v := range expected {
+		merged[k] = v
+	}
+	return merged
+}
+
+// configHashUpToDate returns true if the configHashAnnotation is set to the
+// same value in existing and expected, or is absent from both.
+func configHashUpToDate(existing, expected map[string]string) bool {
+	existingHash, existingOK := existing[configHashAnnotation]
+	expectedHash, expectedOK := expected[configHashAnnotation]
+	return existingOK == expectedOK && existingHash == expectedHash
+}
+
+// containersUpToDate returns true if the fields of the existing containers
+// that are managed by the controller match the expected containers.
+func containersUpToDate(existing, expected []core.Container) bool {
+	if len(existing) != len(expected) {
+		return false
+	}
+	for i := range expected {
+		e, c := expected[i], existing[i]
+		if e.Name != c.Name || e.Image != c.Image ||
+			!apiequality.Semantic.DeepEqual(e.Command, c.Command) ||
+			!apiequality.Semantic.DeepEqual(e.Args, c.Args) ||
+			!apiequality.Semantic.DeepEqual(e.EnvFrom, c.EnvFrom) ||
+			!apiequality.Semantic.DeepEqual(e.Env, c.Env) ||
+			!apiequality.Semantic.DeepEqual(e.Ports, c.Ports) ||
+			!apiequality.Semantic.DeepEqual(e.Resources, c.Resources) ||
+			!apiequality.Semantic.DeepEqual(e.VolumeMounts, c.VolumeMounts) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// reconcileHealth reports a failure of the health check of the workload of
+// the given MyKind, as last observed by the reconcile, and rolls its spec
+// back to the last known good revision if spec.healthCheck.autoRollback is
+// set. Events are only recorded when the reason for the failure changes
+// from the given previous reason, so that they are not repeated on every
+// reconcile.
+func (r *MyKindReconciler) reconcileHealth(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, previousReason string, workload workloadObject) error {
+	reason, message := checkHealth(*myKind, summarizeWorkload(workload))
+	if reason == "" {
+		return nil
+	}
+	transition := reason != previousReason
+	log = log.WithValues("reason", reason)
+
+	if transition {
+		log.Info("workload failed health check", "message", message)
+		r.Recorder.Eventf(myKind, core.EventTypeWarning, "HealthCheckFailed", "Health check failed: %s", message)
+	}
+	if myKind.Spec.HealthCheck == nil || !myKind.Spec.HealthCheck.AutoRollback {
+		return nil
+	}
+
+	lastKnownGood := myKind.Status.LastKnownGoodRevision
+	if lastKnownGood == "" || lastKnownGood == myKind.Status.CurrentRevision {
+		if transition {
+			log.Info("not rolling back, no other known good revision", "last_known_good_revision", lastKnownGood)
+			r.Recorder.Eventf(myKind, core.EventTypeNormal, "AutoRollbackSkipped", "Not rolling back: no known good revision other than the current one")
+		}
+		return nil
+	}
+
+	revisions, err := r.listOwnedRevisions(ctx, myKind)
+	if err != nil {
+		log.Error(err, "failed to list ControllerRevisions for MyKind resource")
+		return err
+	}
+	var revision *apps.ControllerRevision
+	for i := range revisions {
+		if revisions[i].Name == lastKnownGood {
+			revision = &revisions[i]
+		}
+	}
+	if revision == nil {
+		if transition {
+			log.Info("not rolling back, last known good revision not found", "last_known_good_revision", lastKnownGood)
+			r.Recorder.Eventf(myKind, core.EventTypeWarning, "AutoRollbackSkipped", "Not rolling back: controller revision %q not found", lastKnownGood)
+		}
+		return nil
+	}
+
+	// The update to the MyKind triggers another reconcile, which
+	// reconciles the restored spec.
+	log.Info("rolling back to last known good revision", "last_known_good_revision", lastKnownGood)
+	r.Recorder.Eventf(myKind, core.EventTypeWarning, "AutoRollback", "Rolling back to last known good revision %d: %s", revision.Revision, message)
+	return r.restoreRevision(ctx, log, myKind, revision)
+}
+
+// checkHealth returns the reason and message for the failure of the health
+// check of the given workload of the given MyKind, or empty strings if it
+// has not failed.
+// A workload has failed if it reports that it is unable to make progress,
+// or, once a revision of the MyKind has been rolled out, if fewer than
+// spec.healthCheck.minReadyPercentage of its desired replicas are ready.
+func checkHealth(myKind mygroupv1.MyKind, workload *workloadSummary) (string, string) {
+	if workload == nil {
+		return "", ""
+	}
+	if workload.failureReason != "" {
+		return workload.failureReason, workload.failureMessage
+	}
+
+	healthCheck := myKind.Spec.HealthCheck
+	if healthCheck == nil || healthCheck.MinReadyPercentage == nil || myKind.Status.LastKnownGoodRevision == "" || workload.desiredReplicas == 0 {
+		return "", ""
+	}
+	if workload.readyReplicas*100 < *healthCheck.MinReadyPercentage*workload.desiredReplicas {
+		return "InsufficientReadyReplicas", fmt.Sprintf("%d of %d replicas of %s %q are ready, below the minimum of %d%%",
+			workload.readyReplicas, workload.desiredReplicas, workloadKindName(workloadKind(myKind)), workload.name, *healthCheck.MinReadyPercentage)
+	}
+	return "", ""
+}
+
+// degradedReason returns the reason of the Degraded condition of the given
+// status, or an empty string if the MyKind is not Degraded.
+func degradedReason(status mygroupv1.MyKindStatus) string {
+	for _, c := range status.Conditions {
+		if c.Type == mygroupv1.MyKindDegraded && c.Status == core.ConditionTrue {
+			return c.Reason
+		}
+	}
+	return ""
+}
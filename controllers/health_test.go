@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+func TestCheckHealth(t *testing.T) {
+	healthCheck := &mygroupv1.HealthCheckSpec{MinReadyPercentage: pointer.Int32Ptr(50)}
+
+	tests := []struct {
+		name          string
+		healthCheck   *mygroupv1.HealthCheckSpec
+		lastKnownGood string
+		workload      *workloadSummary
+		wantReason    string
+	}{
+		{
+			name:     "no workload",
+			workload: nil,
+		},
+		{
+			name:       "progress deadline exceeded",
+			workload:   &workloadSummary{name: "web", desiredReplicas: 2, readyReplicas: 2, failureReason: "ProgressDeadlineExceeded", failureMessage: "ReplicaSet \"web-1\" has timed out progressing."},
+			wantReason: "ProgressDeadlineExceeded",
+		},
+		{
+			name:          "ready replicas not checked",
+			lastKnownGood: "test-a",
+			workload:      &workloadSummary{name: "web", desiredReplicas: 4},
+		},
+		{
+			name:        "no known good revision",
+			healthCheck: healthCheck,
+			workload:    &workloadSummary{name: "web", desiredReplicas: 4},
+		},
+		{
+			name:          "enough ready replicas",
+			healthCheck:   healthCheck,
+			lastKnownGood: "test-a",
+			workload:      &workloadSummary{name: "web", desiredReplicas: 4, readyReplicas: 2},
+		},
+		{
+			name:          "insufficient ready replicas",
+			healthCheck:   healthCheck,
+			lastKnownGood: "test-a",
+			workload:      &workloadSummary{name: "web", desiredReplicas: 4, readyReplicas: 1},
+			wantReason:    "InsufficientReadyReplicas",
+		},
+		{
+			name:          "scaled to zero",
+			healthCheck:   healthCheck,
+			lastKnownGood: "test-a",
+			workload:      &workloadSummary{name: "web"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			myKind := mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment:  mygroupv1.DeploymentSpec{Name: "web"},
+					HealthCheck: tt.healthCheck,
+				},
+				Status: mygroupv1.MyKindStatus{LastKnownGoodRevision: tt.lastKnownGood},
+			}
+			reason, message := checkHealth(myKind, tt.workload)
+			if reason != tt.wantReason {
+				t.Errorf("expected reason %q, got %q", tt.wantReason, reason)
+			}
+			if (reason == "") != (message == "") {
+				t.Errorf("expected a message only with a reason, got reason %q and message %q", reason, message)
+			}
+		})
+	}
+}
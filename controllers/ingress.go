@@ -0,0 +1,222 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// ingressClassAnnotation is the annotation used to select the ingress
+// controller that should satisfy an Ingress.
+const ingressClassAnnotation = "kubernetes.io/ingress.class"
+
+// reconcileIngress ensures that the Ingress for the given MyKind exists and
+// is up to date if spec.ingress is set, and cleans up any Ingresses
+// previously created for it.
+// It returns the Ingress as last observed or written by the controller,
+// which will be nil if spec.ingress is not set or it could not be retrieved
+// or created.
+func (r *MyKindReconciler) reconcileIngress(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) (*networking.Ingress, error) {
+	if err := r.cleanupOwnedIngresses(ctx, log, myKind); err != nil {
+		log.Error(err, "failed to clean up old Ingress resources for this MyKind")
+		return nil, err
+	}
+
+	if myKind.Spec.Ingress == nil {
+		return nil, nil
+	}
+
+	log = log.WithValues("ingress_name", myKind.Spec.Deployment.Name)
+
+	log.Info("checking if an existing Ingress exists for this resource")
+	ingress := networking.Ingress{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: myKind.Namespace, Name: myKind.Spec.Deployment.Name}, &ingress)
+	if apierrors.IsNotFound(err) {
+		log.Info("could not find existing Ingress for MyKind, creating one...")
+
+		ingress = *buildIngress(*myKind)
+		if err := r.apply(ctx, &ingress); err != nil {
+			log.Error(err, "failed to create Ingress resource")
+			return nil, err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Created", "Created ingress %q", ingress.Name)
+		log.Info("created Ingress resource for MyKind")
+		return &ingress, nil
+	}
+	if err != nil {
+		log.Error(err, "failed to get Ingress for MyKind resource")
+		return nil, err
+	}
+
+	if !metav1.IsControlledBy(&ingress, myKind) {
+		return nil, fmt.Errorf("ingress %q already exists and is not controlled by this MyKind", ingress.Name)
+	}
+
+	log.Info("checking the Ingress for drift")
+	desired := buildIngress(*myKind)
+	if corrected := correctIngressDrift(ingress.DeepCopy(), desired); len(corrected) > 0 {
+		log.Info("applying Ingress", "fields", corrected)
+
+		if err := r.apply(ctx, desired); err != nil {
+			log.Error(err, "failed to apply Ingress")
+			return &ingress, err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "DriftCorrected", "Corrected drift in ingress %q: %s", desired.Name, strings.Join(corrected, ", "))
+
+		return desired, nil
+	}
+
+	log.Info("Ingress up to date")
+
+	return &ingress, nil
+}
+
+// cleanupOwnedIngresses will Delete any existing Ingress resources that were
+// created for the given MyKind that no longer match the
+// myKind.spec.deployment.name field, or all of them if myKind.spec.ingress
+// is not set.
+func (r *MyKindReconciler) cleanupOwnedIngresses(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) error {
+	log.Info("finding existing Ingresses for MyKind resource")
+
+	// List all ingress resources owned by this MyKind
+	var ingresses networking.IngressList
+	if err := r.List(ctx, &ingresses, client.InNamespace(myKind.Namespace), client.MatchingField(ingressOwnerKey, myKind.Name)); err != nil {
+		return err
+	}
+
+	deleted := 0
+	for _, ing := range ingresses.Items {
+		if myKind.Spec.Ingress != nil && ing.Name == myKind.Spec.Deployment.Name {
+			// If this ingress's name matches the one on the MyKind resource
+			// then do not delete it.
+			continue
+		}
+
+		if err := r.Client.Delete(ctx, &ing); err != nil {
+			log.Error(err, "failed to delete Ingress resource")
+			return err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Deleted", "Deleted ingress %q", ing.Name)
+		deleted++
+	}
+
+	log.Info("finished cleaning up old Ingress resources", "number_deleted", deleted)
+
+	return nil
+}
+
+// buildIngress renders the Ingress for the given MyKind, which must have
+// spec.ingress set. It routes each of the hosts and paths to the Service
+// created for the MyKind.
+func buildIngress(myKind mygroupv1.MyKind) *networking.Ingress {
+	spec := myKind.Spec.Ingress.DeepCopy()
+
+	annotations := spec.Annotations
+	if spec.IngressClass != "" {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[ingressClassAnnotation] = spec.IngressClass
+	}
+
+	backend := networking.IngressBackend{
+		ServiceName: myKind.Spec.Deployment.Name,
+		ServicePort: spec.ServicePort,
+	}
+
+	var paths []networking.HTTPIngressPath
+	for _, p := range spec.Paths {
+		paths = append(paths, networking.HTTPIngressPath{Path: p, Backend: backend})
+	}
+
+	hosts := spec.Hosts
+	if len(hosts) == 0 {
+		// A rule without a host matches all hosts.
+		hosts = []string{""}
+	}
+	var rules []networking.IngressRule
+	for _, host := range hosts {
+		rules = append(rules, networking.IngressRule{
+			Host: host,
+			IngressRuleValue: networking.IngressRuleValue{
+				HTTP: &networking.HTTPIngressRuleValue{Paths: paths},
+			},
+		})
+	}
+
+	var tls []networking.IngressTLS
+	if spec.TLSSecretName != "" {
+		tls = []networking.IngressTLS{{Hosts: spec.Hosts, SecretName: spec.TLSSecretName}}
+	}
+
+	ingress := networking.Ingress{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: networking.SchemeGroupVersion.String(),
+			Kind:       "Ingress",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            myKind.Spec.Deployment.Name,
+			Namespace:       myKind.Namespace,
+			Labels:          deploymentLabels(myKind),
+			Annotations:     annotations,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(&myKind, mygroupv1.GroupVersion.WithKind("MyKind"))},
+		},
+		Spec: networking.IngressSpec{
+			TLS:   tls,
+			Rules: rules,
+		},
+	}
+	return &ingress
+}
+
+// correctIngressDrift compares the fields of an existing Ingress that are
+// owned by the controller against the desired Ingress built for a MyKind,
+// and copies the desired values onto existing wherever they differ.
+// It returns the path of each field that was corrected.
+func correctIngressDrift(existing, desired *networking.Ingress) []string {
+	var corrected []string
+
+	if !labelsUpToDate(existing.Labels, desired.Labels) {
+		existing.Labels = mergeLabels(existing.Labels, desired.Labels)
+		corrected = append(corrected, "metadata.labels")
+	}
+	if !labelsUpToDate(existing.Annotations, desired.Annotations) {
+		existing.Annotations = mergeLabels(existing.Annotations, desired.Annotations)
+		corrected = append(corrected, "metadata.annotations")
+	}
+	if !apiequality.Semantic.DeepEqual(existing.Spec, desired.Spec) {
+		existing.Spec = desired.Spec
+		corrected = append(corrected, "spec")
+	}
+
+	return corrected
+}
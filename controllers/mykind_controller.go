@@ -18,18 +18,26 @@ package controllers
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-logr/logr"
 	apps "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	core "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	networking "k8s.io/api/networking/v1beta1"
+	policy "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	mygroupv1beta1 "jetstack.io/example-controller/api/v1beta1"
+	mygroupv1 "jetstack.io/example-controller/api/v1"
 )
 
 // MyKindReconciler reconciles a MyKind object
@@ -38,12 +46,37 @@ type MyKindReconciler struct {
 	Log logr.Logger
 
 	Recorder record.EventRecorder
+
+	// ForceOwnership makes the controller take ownership of fields of the
+	// resources it manages that were last set by another field manager,
+	// instead of failing to reconcile them with a conflict error.
+	ForceOwnership bool
+
+	// Clock is used to tell the time when evaluating schedules and rollout
+	// delays, so that it can be controlled in tests. If nil, the system
+	// clock is used.
+	Clock clock.Clock
+}
+
+// now returns the current time according to the clock of the reconciler.
+func (r *MyKindReconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock.Now()
 }
 
 // +kubebuilder:rbac:groups=mygroup.k8s.io,resources=mykinds,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=mygroup.k8s.io,resources=mykinds/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=mygroup.k8s.io,resources=mykinds/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=statefulsets;daemonsets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch;create;patch;delete
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;patch;delete
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=configmaps;secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *MyKindReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -52,7 +85,7 @@ func (r *MyKindReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	// your logic here
 	log.Info("fetching MyKind resource")
-	myKind := mygroupv1beta1.MyKind{}
+	myKind := mygroupv1.MyKind{}
 	if err := r.Client.Get(ctx, req.NamespacedName, &myKind); err != nil {
 		log.Error(err, "failed to get MyKind resource")
 		// Ignore NotFound errors as they will be retried automatically if the
@@ -60,161 +93,360 @@ func (r *MyKindReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	if err := r.cleanupOwnedResources(ctx, log, &myKind); err != nil {
-		log.Error(err, "failed to clean up old Deployment resources for this MyKind")
-		return ctrl.Result{}, err
-	}
+	if !myKind.DeletionTimestamp.IsZero() {
+		if reason := pauseReason(myKind); reason != "" {
+			log.Info("MyKind resource is being deleted but reconciliation is paused, not applying deletion policy", "reason", reason)
+			return ctrl.Result{}, nil
+		}
 
-	log = log.WithValues("deployment_name", myKind.Spec.DeploymentName)
+		log.Info("MyKind resource is being deleted, applying deletion policy")
+		if err := r.finalize(ctx, log, &myKind); err != nil {
+			// The status can only be updated while the MyKind still exists,
+			// so it is only updated if the deletion policy could not be
+			// applied.
+			return ctrl.Result{}, r.syncStatusAfterError(ctx, log, &myKind, err)
+		}
+		return ctrl.Result{}, nil
+	}
 
-	log.Info("checking if an existing Deployment exists for this resource")
-	deployment := apps.Deployment{}
-	err := r.Client.Get(ctx, client.ObjectKey{Namespace: myKind.Namespace, Name: myKind.Spec.DeploymentName}, &deployment)
-	if apierrors.IsNotFound(err) {
-		log.Info("could not find existing Deployment for MyKind, creating one...")
+	if err := r.ensureFinalizer(ctx, log, &myKind); err != nil {
+		log.Error(err, "failed to add finalizer to MyKind resource")
+		return ctrl.Result{}, r.syncStatusAfterError(ctx, log, &myKind, err)
+	}
 
-		deployment = *buildDeployment(myKind)
-		if err := r.Client.Create(ctx, &deployment); err != nil {
-			log.Error(err, "failed to create Deployment resource")
-			return ctrl.Result{}, err
+	// Defaults are normally applied by the mutating webhook, but are applied
+	// again here in case the resource was persisted without passing through
+	// it.
+	myKind.Default()
+
+	if pauseReason(myKind) == "" && rollbackRequested(myKind) {
+		// The update to the MyKind triggers another reconcile, which
+		// reconciles the restored spec.
+		log.Info("rollback requested, restoring spec from a previous revision")
+		if err := r.rollback(ctx, log, &myKind); err != nil {
+			return ctrl.Result{}, r.syncStatusAfterError(ctx, log, &myKind, err)
 		}
-
-		r.Recorder.Eventf(&myKind, core.EventTypeNormal, "Created", "Created deployment %q", deployment.Name)
-		log.Info("created Deployment resource for MyKind")
 		return ctrl.Result{}, nil
 	}
-	if err != nil {
-		log.Error(err, "failed to get Deployment for MyKind resource")
-		return ctrl.Result{}, err
-	}
 
-	log.Info("existing Deployment resource already exists for MyKind, checking replica count")
+	var observed observedState
+	var result ctrl.Result
+	var err error
+	if reason := pauseReason(myKind); reason != "" {
+		log.Info("reconciliation is paused, not modifying any resources", "reason", reason)
+		observed, err = r.observeResources(ctx, log, &myKind)
+	} else {
+		observed, result, err = r.reconcileResources(ctx, log, &myKind)
+	}
+	r.recordPauseTransition(&myKind)
 
-	expectedReplicas := int32(1)
-	if myKind.Spec.Replicas != nil {
-		expectedReplicas = *myKind.Spec.Replicas
+	previousHealth := degradedReason(myKind.Status)
+	if statusErr := r.syncStatus(ctx, log, &myKind, observed, err); statusErr != nil {
+		log.Error(statusErr, "failed to update MyKind status")
+		if err == nil {
+			err = statusErr
+		}
+		return ctrl.Result{}, err
 	}
-	if *deployment.Spec.Replicas != expectedReplicas {
-		log.Info("updating replica count", "old_count", *deployment.Spec.Replicas, "new_count", expectedReplicas)
 
-		deployment.Spec.Replicas = &expectedReplicas
-		if err := r.Client.Update(ctx, &deployment); err != nil {
-			log.Error(err, "failed to Deployment update replica count")
+	// The health of the workload is only acted on once the resources have
+	// been reconciled, as the revision of the spec is not known otherwise.
+	if err == nil && observed.revision != "" {
+		if err := r.reconcileHealth(ctx, log, &myKind, previousHealth, observed.workload); err != nil {
 			return ctrl.Result{}, err
 		}
+	}
 
-		r.Recorder.Eventf(&myKind, core.EventTypeNormal, "Scaled", "Scaled deployment %q to %d replicas", deployment.Name, expectedReplicas)
+	return result, err
+}
 
-		return ctrl.Result{}, nil
+// reconcileResources ensures that the workload and the other resources
+// managed for the given MyKind exist and are up to date.
+// It returns the resources as last observed or written by the controller.
+func (r *MyKindReconciler) reconcileResources(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) (observedState, ctrl.Result, error) {
+	var observed observedState
+	var result ctrl.Result
+	schedule, untilNextSchedule, err := activeSchedule(*myKind, r.now())
+	if err != nil {
+		log.Error(err, "failed to evaluate schedules")
 	}
+	var revision string
+	if err == nil {
+		revision, err = r.reconcileRevisions(ctx, log, myKind)
+	}
+	if err == nil {
+		scaled := myKind
+		if schedule != nil {
+			log.Info("scaling workload for active schedule", "schedule", schedule.Name, "replicas", schedule.Replicas)
+			scaled = scheduledMyKind(*myKind, *schedule)
+		}
+		observed, result, err = r.reconcileWorkload(ctx, log, scaled)
+		observed.revision = revision
+		observed.schedule = schedule
+		if err == nil {
+			r.recordScheduleTransition(myKind, schedule)
+		}
+	}
+	if err == nil {
+		var serviceResult ctrl.Result
+		serviceResult, err = r.reconcileService(ctx, log, myKind, observed.blueGreen)
+		result.Requeue = result.Requeue || serviceResult.Requeue
+	}
+	if err == nil {
+		observed.ingress, err = r.reconcileIngress(ctx, log, myKind)
+	}
+	if err == nil {
+		// The workload's replica count may be managed by another actor,
+		// so the disruption budget is based on its current value.
+		replicas := *myKind.Spec.Deployment.Replicas
+		if workload := summarizeWorkload(observed.workload); workload != nil {
+			replicas = workload.desiredReplicas
+		}
+		if observed.canaryDeployment != nil {
+			// The canary pods are selected by the budget too.
+			replicas += deploymentReplicas(observed.canaryDeployment)
+		}
+		if observed.previewDeployment != nil {
+			// So are the pods of the other colour of a blue/green rollout.
+			replicas += deploymentReplicas(observed.previewDeployment)
+		}
 
-	log.Info("replica count up to date", "replica_count", *deployment.Spec.Replicas)
-
-	log.Info("updating MyKind resource status")
-	myKind.Status.ReadyReplicas = deployment.Status.ReadyReplicas
-	if r.Client.Status().Update(ctx, &myKind); err != nil {
-		log.Error(err, "failed to update MyKind status")
-		return ctrl.Result{}, err
+		var pdbResult ctrl.Result
+		pdbResult, err = r.reconcilePodDisruptionBudget(ctx, log, myKind, replicas)
+		result.Requeue = result.Requeue || pdbResult.Requeue
+	}
+	if err == nil {
+		err = r.reconcileHorizontalPodAutoscaler(ctx, log, myKind)
+	}
+	if err != nil {
+		// Resources that were not reached because of the error may still
+		// exist, and are observed as they are so that the status is
+		// accurate.
+		if current, observeErr := r.observeResources(ctx, log, myKind); observeErr == nil {
+			if observed.workload == nil {
+				observed.workload = current.workload
+				observed.canary, observed.canaryDeployment = current.canary, current.canaryDeployment
+				observed.blueGreen, observed.previewDeployment = current.blueGreen, current.previewDeployment
+			}
+			if observed.components == nil {
+				observed.components = current.components
+			}
+			for i := range observed.components {
+				if observed.components[i] == nil {
+					observed.components[i] = current.components[i]
+				}
+			}
+			if observed.ingress == nil {
+				observed.ingress = current.ingress
+			}
+		}
 	}
 
-	log.Info("resource status synced")
+	// The controller is woken up at the next start or end of a schedule to
+	// scale the workload accordingly.
+	if untilNextSchedule > 0 && (result.RequeueAfter == 0 || untilNextSchedule < result.RequeueAfter) {
+		result.RequeueAfter = untilNextSchedule
+	}
 
-	return ctrl.Result{}, nil
+	return observed, result, err
 }
 
-// cleanupOwnedResources will Delete any existing Deployment resources that
-// were created for the given MyKind that no longer match the
-// myKind.spec.deploymentName field.
-func (r *MyKindReconciler) cleanupOwnedResources(ctx context.Context, log logr.Logger, myKind *mygroupv1beta1.MyKind) error {
-	log.Info("finding existing Deployments for MyKind resource")
-
-	// List all deployment resources owned by this MyKind
-	var deployments apps.DeploymentList
-	if err := r.List(ctx, &deployments, client.InNamespace(myKind.Namespace), client.MatchingField(deploymentOwnerKey, myKind.Name)); err != nil {
-		return err
+// replicasManagedExternally returns true if the replica count of the
+// workload of the given kind and name is managed by a
+// HorizontalPodAutoscaler, in which case the controller should not attempt
+// to change it.
+func (r *MyKindReconciler) replicasManagedExternally(ctx context.Context, namespace, kind, name string) (bool, error) {
+	var hpas autoscalingv2beta2.HorizontalPodAutoscalerList
+	if err := r.List(ctx, &hpas, client.InNamespace(namespace)); err != nil {
+		return false, err
 	}
 
-	deleted := 0
-	for _, depl := range deployments.Items {
-		if depl.Name == myKind.Spec.DeploymentName {
-			// If this deployment's name matches the one on the MyKind resource
-			// then do not delete it.
-			continue
+	for _, hpa := range hpas.Items {
+		ref := hpa.Spec.ScaleTargetRef
+		if ref.Kind == kind && ref.Name == name {
+			return true, nil
 		}
-
-		if err := r.Client.Delete(ctx, &depl); err != nil {
-			log.Error(err, "failed to delete Deployment resource")
-			return err
-		}
-
-		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Deleted", "Deleted deployment %q", depl.Name)
-		deleted++
 	}
 
-	log.Info("finished cleaning up old Deployment resources", "number_deleted", deleted)
+	return false, nil
+}
 
-	return nil
+// deploymentLabels returns the labels used to select the pods of the
+// workload created for the given MyKind.
+func deploymentLabels(myKind mygroupv1.MyKind) map[string]string {
+	return map[string]string{
+		mygroupv1.DeploymentNameLabel: myKind.Spec.Deployment.Name,
+	}
 }
 
-func buildDeployment(myKind mygroupv1beta1.MyKind) *apps.Deployment {
+// buildDeployment renders the Deployment for the given MyKind. configHash is
+// the hash of the ConfigMaps and Secrets it references, and is recorded on
+// the pod template if it is not empty.
+func buildDeployment(myKind mygroupv1.MyKind, configHash string) *apps.Deployment {
 	deployment := apps.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apps.SchemeGroupVersion.String(),
+			Kind:       "Deployment",
+		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:            myKind.Spec.DeploymentName,
+			Name:            myKind.Spec.Deployment.Name,
 			Namespace:       myKind.Namespace,
-			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(&myKind, mygroupv1beta1.GroupVersion.WithKind("MyKind"))},
+			Labels:          deploymentLabels(myKind),
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(&myKind, mygroupv1.GroupVersion.WithKind("MyKind"))},
 		},
 		Spec: apps.DeploymentSpec{
-			Replicas: myKind.Spec.Replicas,
+			Replicas: initialReplicas(myKind),
 			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"example-controller.jetstack.io/deployment-name": myKind.Spec.DeploymentName,
-				},
-			},
-			Template: core.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"example-controller.jetstack.io/deployment-name": myKind.Spec.DeploymentName,
-					},
-				},
-				Spec: core.PodSpec{
-					Containers: []core.Container{
-						{
-							Name:  "nginx",
-							Image: "nginx:latest",
-						},
-					},
-				},
+				MatchLabels: deploymentLabels(myKind),
 			},
+			Template: *buildPodTemplate(myKind, configHash),
 		},
 	}
 	return &deployment
 }
 
-var (
-	deploymentOwnerKey = ".metadata.controller"
-)
+// buildPodTemplate renders the pod template of the workload for the given
+// MyKind. configHash is the hash of the ConfigMaps and Secrets it
+// references, and is recorded on the pod template if it is not empty.
+func buildPodTemplate(myKind mygroupv1.MyKind, configHash string) *core.PodTemplateSpec {
+	var annotations map[string]string
+	if configHash != "" {
+		annotations = map[string]string{configHashAnnotation: configHash}
+	}
+	volumes, _, _ := buildConfigVolumes(myKind)
 
-func (r *MyKindReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	if err := mgr.GetFieldIndexer().IndexField(&apps.Deployment{}, deploymentOwnerKey, func(rawObj runtime.Object) []string {
-		// grab the Deployment object, extract the owner...
-		depl := rawObj.(*apps.Deployment)
-		owner := metav1.GetControllerOf(depl)
-		if owner == nil {
-			return nil
+	return &core.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      deploymentLabels(myKind),
+			Annotations: annotations,
+		},
+		Spec: core.PodSpec{
+			Containers: buildContainers(myKind),
+			Volumes:    volumes,
+		},
+	}
+}
+
+// initialReplicas returns the number of replicas that a new workload for
+// the given MyKind is created with. If autoscaling is enabled this is kept
+// within the bounds of the HorizontalPodAutoscaler.
+func initialReplicas(myKind mygroupv1.MyKind) *int32 {
+	replicas := *myKind.Spec.Deployment.Replicas
+	if a := myKind.Spec.Autoscaling; a != nil {
+		if a.MinReplicas != nil && replicas < *a.MinReplicas {
+			replicas = *a.MinReplicas
 		}
-		// ...make sure it's a MyKind...
-		if owner.APIVersion != mygroupv1beta1.GroupVersion.String() || owner.Kind != "MyKind" {
-			return nil
+		if replicas > a.MaxReplicas {
+			replicas = a.MaxReplicas
 		}
+	}
+	return &replicas
+}
+
+// buildContainers renders the containers for the pod template of the
+// workload created for the given MyKind.
+// The MyKind is expected to have had defaults applied, so that the result
+// can be compared against an existing workload.
+func buildContainers(myKind mygroupv1.MyKind) []core.Container {
+	spec := myKind.Spec.Deployment.Container.DeepCopy()
+	_, mounts, envFrom := buildConfigVolumes(myKind)
+
+	container := core.Container{
+		Name:         spec.Name,
+		Image:        spec.Image,
+		Command:      spec.Command,
+		Args:         spec.Args,
+		EnvFrom:      envFrom,
+		Env:          spec.Env,
+		Ports:        spec.Ports,
+		Resources:    spec.Resources,
+		VolumeMounts: mounts,
+	}
+
+	return []core.Container{container}
+}
 
-		// ...and if so, return it
-		return []string{owner.Name}
-	}); err != nil {
+var (
+	deploymentOwnerKey  = ".metadata.controller"
+	statefulSetOwnerKey = ".metadata.controller"
+	daemonSetOwnerKey   = ".metadata.controller"
+	serviceOwnerKey     = ".metadata.controller"
+	ingressOwnerKey     = ".metadata.controller"
+	pdbOwnerKey         = ".metadata.controller"
+	hpaOwnerKey         = ".metadata.controller"
+	revisionOwnerKey    = ".metadata.controller"
+)
+
+// indexByMyKindController returns the name of the MyKind that controls the
+// given object, if any, for use as a field index.
+func indexByMyKindController(rawObj runtime.Object) []string {
+	// grab the object, extract the owner...
+	obj, err := meta.Accessor(rawObj)
+	if err != nil {
+		return nil
+	}
+	owner := metav1.GetControllerOf(obj)
+	if owner == nil {
+		return nil
+	}
+	// ...make sure it's a MyKind...
+	// Only the group is compared, as resources created by earlier versions
+	// of the controller are owned via the v1beta1 API.
+	gv, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err != nil || gv.Group != mygroupv1.GroupVersion.Group || owner.Kind != "MyKind" {
+		return nil
+	}
+
+	// ...and if so, return it
+	return []string{owner.Name}
+}
+
+func (r *MyKindReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(&apps.Deployment{}, deploymentOwnerKey, indexByMyKindController); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(&apps.StatefulSet{}, statefulSetOwnerKey, indexByMyKindController); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(&apps.DaemonSet{}, daemonSetOwnerKey, indexByMyKindController); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(&core.Service{}, serviceOwnerKey, indexByMyKindController); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(&networking.Ingress{}, ingressOwnerKey, indexByMyKindController); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(&policy.PodDisruptionBudget{}, pdbOwnerKey, indexByMyKindController); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(&autoscalingv2beta2.HorizontalPodAutoscaler{}, hpaOwnerKey, indexByMyKindController); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(&apps.ControllerRevision{}, revisionOwnerKey, indexByMyKindController); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(&mygroupv1.MyKind{}, configMapRefKey, indexConfigMapReferences); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(&mygroupv1.MyKind{}, secretRefKey, indexSecretReferences); err != nil {
 		return err
 	}
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&mygroupv1beta1.MyKind{}).
+		For(&mygroupv1.MyKind{}).
 		Owns(&apps.Deployment{}).
+		Owns(&apps.StatefulSet{}).
+		Owns(&apps.DaemonSet{}).
+		Owns(&core.Service{}).
+		Owns(&networking.Ingress{}).
+		Owns(&policy.PodDisruptionBudget{}).
+		Owns(&autoscalingv2beta2.HorizontalPodAutoscaler{}).
+		Watches(&source.Kind{Type: &core.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: r.myKindsReferencing(configMapRefKey),
+		}).
+		Watches(&source.Kind{Type: &core.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: r.myKindsReferencing(secretRefKey),
+		}).
 		Complete(r)
 }
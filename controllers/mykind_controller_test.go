@@ -18,17 +18,32 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	apps "k8s.io/api/apps/v1"
+	autoscaling "k8s.io/api/autoscaling/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1beta1"
+	policy "k8s.io/api/policy/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 
-	mygroupv1beta1 "jetstack.io/example-controller/api/v1beta1"
+	mygroupv1 "jetstack.io/example-controller/api/v1"
 )
 
 var _ = Context("Inside of a new namespace", func() {
@@ -38,13 +53,15 @@ var _ = Context("Inside of a new namespace", func() {
 	Describe("when no existing resources exist", func() {
 
 		It("should create a new Deployment resource with the specified name and one replica if none is provided", func() {
-			myKind := &mygroupv1beta1.MyKind{
+			myKind := &mygroupv1.MyKind{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "testresource",
 					Namespace: ns.Name,
 				},
-				Spec: mygroupv1beta1.MyKindSpec{
-					DeploymentName: "deployment-name",
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: "deployment-name",
+					},
 				},
 			}
 
@@ -60,14 +77,16 @@ var _ = Context("Inside of a new namespace", func() {
 		})
 
 		It("should create a new Deployment resource with the specified name and two replicas if two is specified", func() {
-			myKind := &mygroupv1beta1.MyKind{
+			myKind := &mygroupv1.MyKind{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "testresource",
 					Namespace: ns.Name,
 				},
-				Spec: mygroupv1beta1.MyKindSpec{
-					DeploymentName: "deployment-name",
-					Replicas:       pointer.Int32Ptr(2),
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name:     "deployment-name",
+						Replicas: pointer.Int32Ptr(2),
+					},
 				},
 			}
 
@@ -91,13 +110,15 @@ var _ = Context("Inside of a new namespace", func() {
 				Name:      "testresource",
 				Namespace: ns.Name,
 			}
-			myKind := &mygroupv1beta1.MyKind{
+			myKind := &mygroupv1.MyKind{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      myKindObjectKey.Name,
 					Namespace: myKindObjectKey.Namespace,
 				},
-				Spec: mygroupv1beta1.MyKindSpec{
-					DeploymentName: deploymentObjectKey.Name,
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: deploymentObjectKey.Name,
+					},
 				},
 			}
 
@@ -114,7 +135,7 @@ var _ = Context("Inside of a new namespace", func() {
 			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
 			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
 
-			myKind.Spec.Replicas = pointer.Int32Ptr(2)
+			myKind.Spec.Deployment.Replicas = pointer.Int32Ptr(2)
 			err = k8sClient.Update(ctx, myKind)
 			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
 
@@ -122,26 +143,1800 @@ var _ = Context("Inside of a new namespace", func() {
 				Should(Equal(int32(2)), "expected Deployment resource to be scale to 2 replicas")
 		})
 
+		It("should create a Deployment running the specified container image", func() {
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testresource",
+					Namespace: ns.Name,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: "deployment-name",
+						Container: mygroupv1.ContainerSpec{
+							Name:  "app",
+							Image: "busybox:1.30",
+							Args:  []string{"sleep", "3600"},
+						},
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			deployment := &apps.Deployment{}
+			Eventually(
+				getResourceFunc(ctx, client.ObjectKey{Name: "deployment-name", Namespace: myKind.Namespace}, deployment),
+				time.Second*5, time.Millisecond*500).Should(BeNil())
+
+			Expect(deployment.Spec.Template.Spec.Containers).To(HaveLen(1))
+			container := deployment.Spec.Template.Spec.Containers[0]
+			Expect(container.Name).To(Equal("app"))
+			Expect(container.Image).To(Equal("busybox:1.30"))
+			Expect(container.Args).To(Equal([]string{"sleep", "3600"}))
+		})
+
+		It("should roll the Deployment when the container image is changed", func() {
+			deploymentObjectKey := client.ObjectKey{
+				Name:      "deployment-name",
+				Namespace: ns.Name,
+			}
+			myKindObjectKey := client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: deploymentObjectKey.Name,
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			deployment := &apps.Deployment{}
+			Eventually(
+				getResourceFunc(ctx, deploymentObjectKey, deployment),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "deployment resource should exist")
+
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:latest"), "image should default to nginx:latest")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+
+			myKind.Spec.Deployment.Container.Image = "nginx:1.17"
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(getDeploymentImageFunc(ctx, deploymentObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal("nginx:1.17"), "expected Deployment resource to be updated with the new image")
+		})
+
+		It("should correct manual changes made to the Deployment's containers", func() {
+			deploymentObjectKey := client.ObjectKey{
+				Name:      "deployment-name",
+				Namespace: ns.Name,
+			}
+			myKindObjectKey := client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: deploymentObjectKey.Name,
+						Container: mygroupv1.ContainerSpec{
+							Env: []core.EnvVar{{Name: "GREETING", Value: "hello"}},
+						},
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			deployment := &apps.Deployment{}
+			Eventually(
+				getResourceFunc(ctx, deploymentObjectKey, deployment),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "deployment resource should exist")
+
+			deployment.Spec.Template.Spec.Containers[0].Env = []core.EnvVar{{Name: "GREETING", Value: "edited"}}
+			deployment.Spec.Template.Labels["unmanaged-label"] = "kept"
+			err = k8sClient.Update(ctx, deployment)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update Deployment resource")
+
+			Eventually(getDeploymentEnvFunc(ctx, deploymentObjectKey, "GREETING"), time.Second*5, time.Millisecond*500).
+				Should(Equal("hello"), "expected manual change to the container env to be reverted")
+
+			err = k8sClient.Get(ctx, deploymentObjectKey, deployment)
+			Expect(err).NotTo(HaveOccurred(), "failed to get Deployment resource")
+			Expect(deployment.Spec.Template.Labels).To(HaveKeyWithValue("unmanaged-label", "kept"))
+
+			Eventually(getMyKindEventMessagesFunc(ctx, myKindObjectKey, "DriftCorrected"), time.Second*5, time.Millisecond*500).
+				Should(ContainElement(fmt.Sprintf("Corrected drift in deployment %q: spec.template.spec.containers", deploymentObjectKey.Name)),
+					"expected a DriftCorrected event listing the corrected fields")
+		})
+
+		It("should not manage the replica count of a Deployment scaled by a HorizontalPodAutoscaler", func() {
+			deploymentObjectKey := client.ObjectKey{
+				Name:      "deployment-name",
+				Namespace: ns.Name,
+			}
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testresource",
+					Namespace: ns.Name,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: deploymentObjectKey.Name,
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			deployment := &apps.Deployment{}
+			Eventually(
+				getResourceFunc(ctx, deploymentObjectKey, deployment),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "deployment resource should exist")
+
+			hpa := &autoscaling.HorizontalPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "hpa",
+					Namespace: ns.Name,
+				},
+				Spec: autoscaling.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: autoscaling.CrossVersionObjectReference{
+						APIVersion: "apps/v1",
+						Kind:       "Deployment",
+						Name:       deploymentObjectKey.Name,
+					},
+					MaxReplicas: 5,
+				},
+			}
+			err = k8sClient.Create(ctx, hpa)
+			Expect(err).NotTo(HaveOccurred(), "failed to create HorizontalPodAutoscaler resource")
+
+			deployment.Spec.Replicas = pointer.Int32Ptr(3)
+			err = k8sClient.Update(ctx, deployment)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update Deployment resource")
+
+			Consistently(getDeploymentReplicasFunc(ctx, deploymentObjectKey), time.Second*2, time.Millisecond*500).
+				Should(Equal(int32(3)), "expected replica count set by the autoscaler to be left alone")
+		})
+
+		It("should report the state of the Deployment in the MyKind status", func() {
+			myKindObjectKey := client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: "deployment-name",
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(getMyKindObservedGenerationFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(myKind.Generation), "expected MyKind status to be updated")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+
+			Expect(myKind.Status.DeploymentName).To(Equal("deployment-name"))
+			// There is no Deployment controller running in the test
+			// environment, so the Deployment never becomes available.
+			Expect(getMyKindConditionStatus(myKind, mygroupv1.MyKindReady)).To(Equal(core.ConditionFalse))
+			Expect(getMyKindConditionStatus(myKind, mygroupv1.MyKindProgressing)).To(Equal(core.ConditionTrue))
+			Expect(getMyKindConditionStatus(myKind, mygroupv1.MyKindDegraded)).To(Equal(core.ConditionFalse))
+			Expect(getMyKindConditionStatus(myKind, mygroupv1.MyKindDeploymentAvailable)).To(Equal(core.ConditionUnknown))
+			Expect(getMyKindConditionStatus(myKind, mygroupv1.MyKindDeploymentNameConflict)).To(Equal(core.ConditionFalse))
+		})
+
+		It("should scale the Deployment when the MyKind is scaled using the scale subresource", func() {
+			deploymentObjectKey := client.ObjectKey{
+				Name:      "deployment-name",
+				Namespace: ns.Name,
+			}
+			myKindObjectKey := client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: deploymentObjectKey.Name,
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			deployment := &apps.Deployment{}
+			Eventually(
+				getResourceFunc(ctx, deploymentObjectKey, deployment),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "deployment resource should exist")
+
+			err = updateMyKindScale(myKindObjectKey, 3)
+			Expect(err).NotTo(HaveOccurred(), "failed to update MyKind scale subresource")
+
+			Eventually(getDeploymentReplicasFunc(ctx, deploymentObjectKey)).
+				Should(Equal(int32(3)), "expected Deployment resource to be scaled to 3 replicas")
+
+			scale, err := getMyKindScale(myKindObjectKey)
+			Expect(err).NotTo(HaveOccurred(), "failed to get MyKind scale subresource")
+			Expect(scale.Spec.Replicas).To(Equal(int32(3)))
+			Expect(scale.Status.Selector).To(Equal("example-controller.jetstack.io/deployment-name=deployment-name"))
+		})
+
 		It("should clean up an old Deployment resource if the deploymentName is changed", func() {
 			deploymentObjectKey := client.ObjectKey{
 				Name:      "deployment-name",
 				Namespace: ns.Name,
 			}
-			newDeploymentObjectKey := client.ObjectKey{
-				Name:      "new-deployment",
+			newDeploymentObjectKey := client.ObjectKey{
+				Name:      "new-deployment",
+				Namespace: ns.Name,
+			}
+			myKindObjectKey := client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: deploymentObjectKey.Name,
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			deployment := &apps.Deployment{}
+			Eventually(
+				getResourceFunc(ctx, deploymentObjectKey, deployment),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "deployment resource should exist")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+
+			myKind.Spec.Deployment.Name = newDeploymentObjectKey.Name
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, deploymentObjectKey, deployment),
+				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "old deployment resource should be deleted")
+
+			Eventually(
+				getResourceFunc(ctx, newDeploymentObjectKey, deployment),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "new deployment resource should be created")
+		})
+	})
+
+	Describe("when a MyKind resource is deleted", func() {
+		deploymentObjectKey := client.ObjectKey{Name: "deployment-name"}
+		myKindObjectKey := client.ObjectKey{Name: "testresource"}
+
+		// createMyKind creates a MyKind with the given deletion policy and
+		// waits for its finalizer to be added and its Deployment to be
+		// created.
+		createMyKind := func(policy mygroupv1.DeletionPolicy) *mygroupv1.MyKind {
+			deploymentObjectKey.Namespace = ns.Name
+			myKindObjectKey.Namespace = ns.Name
+
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: deploymentObjectKey.Name,
+					},
+					DeletionPolicy: policy,
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(getMyKindFinalizersFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(ContainElement(myKindFinalizer), "expected finalizer to be added to MyKind")
+
+			Eventually(
+				getResourceFunc(ctx, deploymentObjectKey, &apps.Deployment{}),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "deployment resource should exist")
+
+			return myKind
+		}
+
+		It("should delete the Deployment if the deletion policy is Delete", func() {
+			myKind := createMyKind(mygroupv1.DeletionPolicyDelete)
+
+			err := k8sClient.Delete(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to delete MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, deploymentObjectKey, &apps.Deployment{}),
+				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "deployment resource should be deleted")
+			Eventually(
+				getResourceFunc(ctx, myKindObjectKey, &mygroupv1.MyKind{}),
+				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "MyKind resource should be deleted")
+		})
+
+		It("should orphan the Deployment if the deletion policy is Orphan", func() {
+			myKind := createMyKind(mygroupv1.DeletionPolicyOrphan)
+
+			err := k8sClient.Delete(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to delete MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, myKindObjectKey, &mygroupv1.MyKind{}),
+				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "MyKind resource should be deleted")
+
+			deployment := &apps.Deployment{}
+			err = k8sClient.Get(ctx, deploymentObjectKey, deployment)
+			Expect(err).NotTo(HaveOccurred(), "deployment resource should not be deleted")
+			Expect(deployment.OwnerReferences).To(BeEmpty(), "expected owner reference to be removed")
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(1)))
+		})
+
+		It("should scale the Deployment to zero and orphan it if the deletion policy is ScaleToZero", func() {
+			myKind := createMyKind(mygroupv1.DeletionPolicyScaleToZero)
+
+			err := k8sClient.Delete(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to delete MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, myKindObjectKey, &mygroupv1.MyKind{}),
+				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "MyKind resource should be deleted")
+
+			deployment := &apps.Deployment{}
+			err = k8sClient.Get(ctx, deploymentObjectKey, deployment)
+			Expect(err).NotTo(HaveOccurred(), "deployment resource should not be deleted")
+			Expect(deployment.OwnerReferences).To(BeEmpty(), "expected owner reference to be removed")
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(0)))
+		})
+	})
+
+	Describe("when a Deployment with the specified name already exists", func() {
+		deploymentObjectKey := client.ObjectKey{Name: "deployment-name"}
+		myKindObjectKey := client.ObjectKey{Name: "testresource"}
+
+		// createDeployment creates a Deployment that was not created by the
+		// controller, with pods labelled with the given labels.
+		createDeployment := func(podLabels map[string]string, owners ...metav1.OwnerReference) {
+			deploymentObjectKey.Namespace = ns.Name
+			myKindObjectKey.Namespace = ns.Name
+
+			deployment := &apps.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            deploymentObjectKey.Name,
+					Namespace:       deploymentObjectKey.Namespace,
+					OwnerReferences: owners,
+				},
+				Spec: apps.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+					Template: core.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+						Spec: core.PodSpec{
+							Containers: []core.Container{{Name: "nginx", Image: "nginx:latest"}},
+						},
+					},
+				},
+			}
+			err := k8sClient.Create(ctx, deployment)
+			Expect(err).NotTo(HaveOccurred(), "failed to create existing Deployment resource")
+		}
+
+		createMyKind := func(policy mygroupv1.AdoptionPolicy) {
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: deploymentObjectKey.Name,
+					},
+					AdoptionPolicy: policy,
+				},
+			}
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+		}
+
+		compatibleLabels := map[string]string{mygroupv1.DeploymentNameLabel: "deployment-name"}
+
+		It("should not adopt the Deployment if the adoption policy is Never", func() {
+			createDeployment(compatibleLabels)
+			createMyKind(mygroupv1.AdoptionPolicyNever)
+
+			Eventually(getMyKindConditionStatusFunc(ctx, myKindObjectKey, mygroupv1.MyKindDeploymentNameConflict), time.Second*5, time.Millisecond*500).
+				Should(Equal(core.ConditionTrue), "expected DeploymentNameConflict condition to be set")
+
+			deployment := &apps.Deployment{}
+			err := k8sClient.Get(ctx, deploymentObjectKey, deployment)
+			Expect(err).NotTo(HaveOccurred(), "failed to get Deployment resource")
+			Expect(deployment.OwnerReferences).To(BeEmpty(), "expected Deployment not to be adopted")
+		})
+
+		It("should adopt an unowned Deployment with a compatible selector if the adoption policy is IfUnowned", func() {
+			createDeployment(compatibleLabels)
+			createMyKind(mygroupv1.AdoptionPolicyIfUnowned)
+
+			Eventually(getDeploymentControllerNameFunc(ctx, deploymentObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(myKindObjectKey.Name), "expected Deployment to be adopted")
+			Eventually(getMyKindConditionStatusFunc(ctx, myKindObjectKey, mygroupv1.MyKindDeploymentNameConflict), time.Second*5, time.Millisecond*500).
+				Should(Equal(core.ConditionFalse))
+		})
+
+		It("should not adopt a Deployment with an incompatible selector", func() {
+			createDeployment(map[string]string{"app": "something-else"})
+			createMyKind(mygroupv1.AdoptionPolicyForce)
+
+			Eventually(getMyKindConditionStatusFunc(ctx, myKindObjectKey, mygroupv1.MyKindDeploymentNameConflict), time.Second*5, time.Millisecond*500).
+				Should(Equal(core.ConditionTrue), "expected DeploymentNameConflict condition to be set")
+			Consistently(getDeploymentControllerNameFunc(ctx, deploymentObjectKey), time.Second*2, time.Millisecond*500).
+				Should(BeEmpty(), "expected Deployment not to be adopted")
+		})
+
+		It("should only adopt a Deployment controlled by another resource if the adoption policy is Force", func() {
+			createDeployment(compatibleLabels, metav1.OwnerReference{
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+				Name:       "other-owner",
+				UID:        "other-owner-uid",
+				Controller: pointer.BoolPtr(true),
+			})
+			createMyKind(mygroupv1.AdoptionPolicyIfUnowned)
+
+			Eventually(getMyKindConditionStatusFunc(ctx, myKindObjectKey, mygroupv1.MyKindDeploymentNameConflict), time.Second*5, time.Millisecond*500).
+				Should(Equal(core.ConditionTrue), "expected DeploymentNameConflict condition to be set")
+			Expect(getDeploymentControllerNameFunc(ctx, deploymentObjectKey)()).To(Equal("other-owner"))
+
+			myKind := &mygroupv1.MyKind{}
+			err := k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.AdoptionPolicy = mygroupv1.AdoptionPolicyForce
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(getDeploymentControllerNameFunc(ctx, deploymentObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(myKindObjectKey.Name), "expected Deployment to be adopted")
+		})
+	})
+
+	Describe("when a Service is specified", func() {
+		It("should create, update and delete a Service selecting the Deployment's pods", func() {
+			serviceObjectKey := client.ObjectKey{
+				Name:      "deployment-name",
+				Namespace: ns.Name,
+			}
+			myKindObjectKey := client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: serviceObjectKey.Name,
+					},
+					Service: &mygroupv1.ServiceSpec{
+						Ports:       []core.ServicePort{{Name: "http", Port: 80}},
+						Annotations: map[string]string{"example.com/annotation": "value"},
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			service := &core.Service{}
+			Eventually(
+				getResourceFunc(ctx, serviceObjectKey, service),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "service resource should exist")
+
+			Expect(service.Spec.Type).To(Equal(core.ServiceTypeClusterIP))
+			Expect(service.Spec.Selector).To(Equal(map[string]string{mygroupv1.DeploymentNameLabel: serviceObjectKey.Name}))
+			Expect(service.Spec.Ports).To(HaveLen(1))
+			Expect(service.Spec.Ports[0].TargetPort.IntValue()).To(Equal(80))
+			Expect(service.Annotations).To(HaveKeyWithValue("example.com/annotation", "value"))
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+
+			myKind.Spec.Service.Ports[0].Port = 8080
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(getServicePortFunc(ctx, serviceObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(int32(8080)), "expected service port to be updated")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+
+			myKind.Spec.Service = nil
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, serviceObjectKey, service),
+				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "service resource should be deleted")
+		})
+	})
+
+	Describe("when an Ingress is specified", func() {
+		It("should create an Ingress routing to the Service and report its load-balancer address", func() {
+			ingressObjectKey := client.ObjectKey{
+				Name:      "deployment-name",
+				Namespace: ns.Name,
+			}
+			myKindObjectKey := client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: ingressObjectKey.Name,
+					},
+					Service: &mygroupv1.ServiceSpec{
+						Ports: []core.ServicePort{{Name: "http", Port: 80}},
+					},
+					Ingress: &mygroupv1.IngressSpec{
+						Hosts:         []string{"example.com"},
+						TLSSecretName: "example-com-tls",
+						IngressClass:  "nginx",
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			ingress := &networking.Ingress{}
+			Eventually(
+				getResourceFunc(ctx, ingressObjectKey, ingress),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "ingress resource should exist")
+
+			Expect(ingress.Annotations).To(HaveKeyWithValue("kubernetes.io/ingress.class", "nginx"))
+			Expect(ingress.Spec.TLS).To(Equal([]networking.IngressTLS{{Hosts: []string{"example.com"}, SecretName: "example-com-tls"}}))
+			Expect(ingress.Spec.Rules).To(HaveLen(1))
+			Expect(ingress.Spec.Rules[0].Host).To(Equal("example.com"))
+			Expect(ingress.Spec.Rules[0].HTTP.Paths).To(Equal([]networking.HTTPIngressPath{{
+				Path: "/",
+				Backend: networking.IngressBackend{
+					ServiceName: ingressObjectKey.Name,
+					ServicePort: intstr.FromString("http"),
+				},
+			}}))
+
+			// There is no ingress controller running in the test environment,
+			// so the load-balancer address is set by hand.
+			ingress.Status.LoadBalancer.Ingress = []core.LoadBalancerIngress{{IP: "192.0.2.1"}}
+			err = k8sClient.Status().Update(ctx, ingress)
+			Expect(err).NotTo(HaveOccurred(), "failed to update Ingress status")
+
+			Eventually(func() []core.LoadBalancerIngress {
+				err := k8sClient.Get(ctx, myKindObjectKey, myKind)
+				Expect(err).NotTo(HaveOccurred(), "failed to get MyKind resource")
+				return myKind.Status.LoadBalancer.Ingress
+			}, time.Second*5, time.Millisecond*500).Should(Equal(ingress.Status.LoadBalancer.Ingress), "expected load-balancer address to be reported")
+
+			myKind.Spec.Ingress = nil
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, ingressObjectKey, ingress),
+				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "ingress resource should be deleted")
+		})
+	})
+
+	Describe("when a disruption budget is specified", func() {
+		It("should create a PodDisruptionBudget that is relaxed or removed as the Deployment is scaled down", func() {
+			pdbObjectKey := client.ObjectKey{
+				Name:      "deployment-name",
+				Namespace: ns.Name,
+			}
+			myKindObjectKey := client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			minAvailable := intstr.FromInt(2)
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name:     pdbObjectKey.Name,
+						Replicas: pointer.Int32Ptr(3),
+					},
+					DisruptionBudget: &mygroupv1.DisruptionBudgetSpec{
+						MinAvailable: &minAvailable,
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			pdb := &policy.PodDisruptionBudget{}
+			Eventually(
+				getResourceFunc(ctx, pdbObjectKey, pdb),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "pod disruption budget resource should exist")
+
+			Expect(pdb.Spec.MinAvailable).To(Equal(&minAvailable))
+			Expect(pdb.Spec.MaxUnavailable).To(BeNil())
+			Expect(pdb.Spec.Selector.MatchLabels).To(Equal(map[string]string{mygroupv1.DeploymentNameLabel: pdbObjectKey.Name}))
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.Deployment.Replicas = pointer.Int32Ptr(1)
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			oneUnavailable := intstr.FromInt(1)
+			Eventually(func() *intstr.IntOrString {
+				pdb := &policy.PodDisruptionBudget{}
+				if err := k8sClient.Get(ctx, pdbObjectKey, pdb); err != nil {
+					return nil
+				}
+				return pdb.Spec.MaxUnavailable
+			}, time.Second*5, time.Millisecond*500).Should(Equal(&oneUnavailable), "expected budget to be relaxed to allow one unavailable pod")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.Deployment.Replicas = pointer.Int32Ptr(0)
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, pdbObjectKey, pdb),
+				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "pod disruption budget resource should be deleted")
+		})
+	})
+
+	Describe("when autoscaling is specified", func() {
+		It("should create a HorizontalPodAutoscaler and stop managing the Deployment's replica count", func() {
+			deploymentObjectKey := client.ObjectKey{
+				Name:      "deployment-name",
+				Namespace: ns.Name,
+			}
+			myKindObjectKey := client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: deploymentObjectKey.Name,
+					},
+					Autoscaling: &mygroupv1.AutoscalingSpec{
+						MinReplicas:                       pointer.Int32Ptr(2),
+						MaxReplicas:                       5,
+						TargetMemoryUtilizationPercentage: pointer.Int32Ptr(70),
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			hpa := &autoscalingv2beta2.HorizontalPodAutoscaler{}
+			Eventually(
+				getResourceFunc(ctx, deploymentObjectKey, hpa),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "horizontal pod autoscaler resource should exist")
+
+			Expect(hpa.Spec.ScaleTargetRef.Kind).To(Equal("Deployment"))
+			Expect(hpa.Spec.ScaleTargetRef.Name).To(Equal(deploymentObjectKey.Name))
+			Expect(*hpa.Spec.MinReplicas).To(Equal(int32(2)))
+			Expect(hpa.Spec.MaxReplicas).To(Equal(int32(5)))
+			Expect(hpa.Spec.Metrics).To(HaveLen(1))
+			Expect(hpa.Spec.Metrics[0].Resource.Name).To(Equal(core.ResourceMemory))
+			Expect(*hpa.Spec.Metrics[0].Resource.Target.AverageUtilization).To(Equal(int32(70)))
+
+			Expect(getDeploymentReplicasFunc(ctx, deploymentObjectKey)()).To(Equal(int32(2)), "expected Deployment to be created with minReplicas")
+
+			// Act as the autoscaler would.
+			deployment := &apps.Deployment{}
+			err = k8sClient.Get(ctx, deploymentObjectKey, deployment)
+			Expect(err).NotTo(HaveOccurred(), "failed to get Deployment resource")
+			deployment.Spec.Replicas = pointer.Int32Ptr(4)
+			err = k8sClient.Update(ctx, deployment)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update Deployment resource")
+
+			Consistently(getDeploymentReplicasFunc(ctx, deploymentObjectKey), time.Second*2, time.Millisecond*500).
+				Should(Equal(int32(4)), "expected replica count set by the autoscaler to be left alone")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.Autoscaling = nil
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, deploymentObjectKey, hpa),
+				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "horizontal pod autoscaler resource should be deleted")
+			Eventually(getDeploymentReplicasFunc(ctx, deploymentObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(int32(1)), "expected replica count to be managed by the MyKind again")
+		})
+	})
+
+	Describe("when a ConfigMap is referenced", func() {
+		It("should mount it and roll out the Deployment when it changes", func() {
+			deploymentObjectKey := client.ObjectKey{
+				Name:      "deployment-name",
+				Namespace: ns.Name,
+			}
+			configMap := &core.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "config",
+					Namespace: ns.Name,
+				},
+				Data: map[string]string{"key": "value"},
+			}
+			err := k8sClient.Create(ctx, configMap)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test ConfigMap resource")
+
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testresource",
+					Namespace: ns.Name,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name:       deploymentObjectKey.Name,
+						ConfigMaps: []mygroupv1.ConfigReference{{Name: configMap.Name, MountPath: "/etc/config"}},
+					},
+				},
+			}
+			err = k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(getDeploymentConfigHashFunc(ctx, deploymentObjectKey), time.Second*5, time.Millisecond*500).
+				ShouldNot(BeEmpty(), "expected Deployment pod template to have a config hash")
+
+			deployment := &apps.Deployment{}
+			err = k8sClient.Get(ctx, deploymentObjectKey, deployment)
+			Expect(err).NotTo(HaveOccurred(), "failed to get Deployment resource")
+			podSpec := deployment.Spec.Template.Spec
+			Expect(podSpec.Volumes).To(HaveLen(1))
+			Expect(podSpec.Volumes[0].ConfigMap.Name).To(Equal(configMap.Name))
+			Expect(podSpec.Containers[0].VolumeMounts).To(HaveLen(1))
+			Expect(podSpec.Containers[0].VolumeMounts[0].MountPath).To(Equal("/etc/config"))
+			oldHash := deployment.Spec.Template.Annotations[configHashAnnotation]
+
+			configMap.Data["key"] = "new-value"
+			err = k8sClient.Update(ctx, configMap)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update ConfigMap resource")
+
+			Eventually(getDeploymentConfigHashFunc(ctx, deploymentObjectKey), time.Second*5, time.Millisecond*500).
+				ShouldNot(Equal(oldHash), "expected config hash to change when the ConfigMap is updated")
+		})
+
+		It("should roll out the Deployment when a referenced Secret changes without hashing its data", func() {
+			deploymentObjectKey := client.ObjectKey{
+				Name:      "deployment-name",
+				Namespace: ns.Name,
+			}
+			secret := &core.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "credentials",
+					Namespace: ns.Name,
+				},
+				Data: map[string][]byte{"password": []byte("hunter2")},
+			}
+			err := k8sClient.Create(ctx, secret)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test Secret resource")
+
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testresource",
+					Namespace: ns.Name,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name:    deploymentObjectKey.Name,
+						Secrets: []mygroupv1.ConfigReference{{Name: secret.Name}},
+					},
+				},
+			}
+			err = k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(getDeploymentConfigHashFunc(ctx, deploymentObjectKey), time.Second*5, time.Millisecond*500).
+				ShouldNot(BeEmpty(), "expected Deployment pod template to have a config hash")
+			oldHash := getDeploymentConfigHashFunc(ctx, deploymentObjectKey)()
+
+			contents, err := json.Marshal([]configContents{{Kind: "Secret", Name: secret.Name, BinaryData: secret.Data}})
+			Expect(err).NotTo(HaveOccurred())
+			sum := sha256.Sum256(contents)
+			Expect(oldHash).NotTo(Equal(hex.EncodeToString(sum[:])), "expected the config hash not to be derived from the Secret data")
+
+			secret.Data["password"] = []byte("correct-horse")
+			err = k8sClient.Update(ctx, secret)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update Secret resource")
+
+			Eventually(getDeploymentConfigHashFunc(ctx, deploymentObjectKey), time.Second*5, time.Millisecond*500).
+				ShouldNot(Equal(oldHash), "expected config hash to change when the Secret is updated")
+		})
+	})
+
+	Describe("when reconciliation is paused", func() {
+		It("should not modify any resources until it is resumed", func() {
+			deploymentObjectKey := client.ObjectKey{
+				Name:      "deployment-name",
+				Namespace: ns.Name,
+			}
+			myKindObjectKey := client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: deploymentObjectKey.Name,
+					},
+					Paused: true,
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(getMyKindConditionStatusFunc(ctx, myKindObjectKey, mygroupv1.MyKindPaused), time.Second*5, time.Millisecond*500).
+				Should(Equal(core.ConditionTrue), "expected Paused condition to be True")
+			Consistently(getResourceFunc(ctx, deploymentObjectKey, &apps.Deployment{}), time.Second*2, time.Millisecond*500).
+				ShouldNot(BeNil(), "deployment resource should not be created while paused")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.Paused = false
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(getResourceFunc(ctx, deploymentObjectKey, &apps.Deployment{}), time.Second*5, time.Millisecond*500).
+				Should(BeNil(), "deployment resource should be created once resumed")
+			Eventually(getMyKindConditionStatusFunc(ctx, myKindObjectKey, mygroupv1.MyKindPaused), time.Second*5, time.Millisecond*500).
+				Should(Equal(core.ConditionFalse), "expected Paused condition to be False")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Annotations = map[string]string{mygroupv1.PausedAnnotation: "true"}
+			myKind.Spec.Deployment.Replicas = pointer.Int32Ptr(3)
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(getMyKindConditionStatusFunc(ctx, myKindObjectKey, mygroupv1.MyKindPaused), time.Second*5, time.Millisecond*500).
+				Should(Equal(core.ConditionTrue), "expected Paused condition to be True")
+			Consistently(getDeploymentReplicasFunc(ctx, deploymentObjectKey), time.Second*2, time.Millisecond*500).
+				Should(Equal(int32(1)), "expected replica count to be left alone while paused")
+		})
+	})
+
+	Describe("when another field manager modifies a Deployment", func() {
+		It("should preserve the fields it set when applying changes", func() {
+			deploymentObjectKey := client.ObjectKey{
+				Name:      "deployment-name",
+				Namespace: ns.Name,
+			}
+			myKindObjectKey := client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: deploymentObjectKey.Name,
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			deployment := &apps.Deployment{}
+			Eventually(
+				getResourceFunc(ctx, deploymentObjectKey, deployment),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "deployment resource should exist")
+
+			managers := map[string]bool{}
+			for _, f := range deployment.ManagedFields {
+				managers[f.Manager] = true
+			}
+			Expect(managers).To(HaveKey(fieldManager), "expected Deployment to be applied by the controller's field manager")
+
+			deployment.Labels["other-label"] = "other-value"
+			deployment.Spec.Template.Spec.TerminationGracePeriodSeconds = pointer.Int64Ptr(60)
+			err = k8sClient.Update(ctx, deployment)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update Deployment resource")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.Deployment.Container.Image = "nginx:1.17"
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(getDeploymentImageFunc(ctx, deploymentObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal("nginx:1.17"), "expected Deployment image to be updated")
+
+			err = k8sClient.Get(ctx, deploymentObjectKey, deployment)
+			Expect(err).NotTo(HaveOccurred(), "failed to get Deployment resource")
+			Expect(deployment.Labels).To(HaveKeyWithValue("other-label", "other-value"))
+			Expect(*deployment.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(int64(60)))
+		})
+
+		It("should take ownership of fields set by another field manager", func() {
+			deploymentObjectKey := client.ObjectKey{
+				Name:      "deployment-name",
+				Namespace: ns.Name,
+			}
+			myKindObjectKey := client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: deploymentObjectKey.Name,
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			deployment := &apps.Deployment{}
+			Eventually(
+				getResourceFunc(ctx, deploymentObjectKey, deployment),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "deployment resource should exist")
+
+			deployment.Spec.Template.Spec.Containers[0].Image = "other:1"
+			err = k8sClient.Update(ctx, deployment)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update Deployment resource")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.Deployment.Container.Image = "nginx:1.17"
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(getDeploymentImageFunc(ctx, deploymentObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal("nginx:1.17"), "expected the controller to take ownership of the Deployment image")
+		})
+	})
+
+	Describe("when the resources of a MyKind change", func() {
+		var myKindObjectKey client.ObjectKey
+		var myKind *mygroupv1.MyKind
+
+		BeforeEach(func() {
+			myKindObjectKey = client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			myKind = &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: "deployment-name",
+					},
+				},
+			}
+		})
+
+		It("should update the status after creating and scaling the Deployment", func() {
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(getMyKindStatusDeploymentNameFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal("deployment-name"), "expected status to report the created Deployment")
+			Eventually(getMyKindObservedGenerationFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(int64(1)), "expected status to observe the first generation")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.Deployment.Replicas = pointer.Int32Ptr(2)
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(getMyKindObservedGenerationFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(int64(2)), "expected status to observe the scaled generation")
+			Expect(getDeploymentReplicasFunc(ctx, client.ObjectKey{Namespace: ns.Name, Name: "deployment-name"})()).
+				To(Equal(int32(2)), "expected Deployment to have been scaled when the status was written")
+		})
+
+		It("should update the status after cleaning up a renamed Deployment", func() {
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(getMyKindStatusDeploymentNameFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal("deployment-name"), "expected status to report the created Deployment")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.Deployment.Name = "new-deployment-name"
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(getMyKindStatusDeploymentNameFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal("new-deployment-name"), "expected status to report the new Deployment")
+			Expect(getMyKindConditionStatusFunc(ctx, myKindObjectKey, mygroupv1.MyKindDegraded)()).
+				To(Equal(core.ConditionFalse), "expected MyKind not to be degraded")
+		})
+
+		It("should update the status when reconciling fails", func() {
+			service := &core.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "deployment-name",
+					Namespace: ns.Name,
+				},
+				Spec: core.ServiceSpec{
+					Ports: []core.ServicePort{{Name: "http", Port: 80}},
+				},
+			}
+			err := k8sClient.Create(ctx, service)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test Service resource")
+
+			myKind.Spec.Service = &mygroupv1.ServiceSpec{
+				Ports: []core.ServicePort{{Name: "http", Port: 80}},
+			}
+			err = k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(getMyKindConditionStatusFunc(ctx, myKindObjectKey, mygroupv1.MyKindDegraded), time.Second*5, time.Millisecond*500).
+				Should(Equal(core.ConditionTrue), "expected MyKind to be degraded")
+			Expect(getMyKindStatusDeploymentNameFunc(ctx, myKindObjectKey)()).
+				To(Equal("deployment-name"), "expected status to report the Deployment despite the error")
+		})
+	})
+
+	Describe("when a workload kind is specified", func() {
+		var myKindObjectKey client.ObjectKey
+		var workloadObjectKey client.ObjectKey
+		var myKind *mygroupv1.MyKind
+
+		BeforeEach(func() {
+			myKindObjectKey = client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			workloadObjectKey = client.ObjectKey{
+				Name:      "deployment-name",
+				Namespace: ns.Name,
+			}
+			myKind = &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: "deployment-name",
+					},
+					WorkloadKind: mygroupv1.WorkloadKindStatefulSet,
+					Service: &mygroupv1.ServiceSpec{
+						Headless: true,
+					},
+				},
+			}
+		})
+
+		It("should create a StatefulSet governed by the Service and report it in the status", func() {
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			statefulSet := &apps.StatefulSet{}
+			Eventually(
+				getResourceFunc(ctx, workloadObjectKey, statefulSet),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "stateful set resource should exist")
+			Expect(statefulSet.Spec.ServiceName).To(Equal("deployment-name"))
+			Expect(*statefulSet.Spec.Replicas).To(Equal(int32(1)))
+
+			Eventually(getMyKindStatusWorkloadKindFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(mygroupv1.WorkloadKindStatefulSet), "expected status to report the StatefulSet")
+
+			// There is no StatefulSet controller running in the test
+			// environment, so the status is set by hand.
+			statefulSet.Status = apps.StatefulSetStatus{
+				ObservedGeneration: statefulSet.Generation,
+				Replicas:           1,
+				ReadyReplicas:      1,
+				UpdatedReplicas:    1,
+			}
+			err = k8sClient.Status().Update(ctx, statefulSet)
+			Expect(err).NotTo(HaveOccurred(), "failed to update StatefulSet status")
+
+			Eventually(getMyKindConditionStatusFunc(ctx, myKindObjectKey, mygroupv1.MyKindReady), time.Second*5, time.Millisecond*500).
+				Should(Equal(core.ConditionTrue), "expected MyKind to be ready once the StatefulSet is")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			Expect(myKind.Status.DeploymentName).To(Equal("deployment-name"))
+			Expect(myKind.Status.ReadyReplicas).To(Equal(int32(1)))
+			Expect(myKind.Status.AvailableReplicas).To(Equal(int32(1)))
+			Expect(getMyKindConditionStatus(myKind, mygroupv1.MyKindDeploymentAvailable)).To(Equal(core.ConditionTrue))
+		})
+
+		It("should create a DaemonSet and report the pods it schedules in the status", func() {
+			myKind.Spec.WorkloadKind = mygroupv1.WorkloadKindDaemonSet
+			myKind.Spec.Service = nil
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			daemonSet := &apps.DaemonSet{}
+			Eventually(
+				getResourceFunc(ctx, workloadObjectKey, daemonSet),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "daemon set resource should exist")
+
+			daemonSet.Status = apps.DaemonSetStatus{
+				ObservedGeneration:     daemonSet.Generation,
+				DesiredNumberScheduled: 3,
+				CurrentNumberScheduled: 3,
+				UpdatedNumberScheduled: 3,
+				NumberReady:            2,
+				NumberAvailable:        2,
+				NumberUnavailable:      1,
+			}
+			err = k8sClient.Status().Update(ctx, daemonSet)
+			Expect(err).NotTo(HaveOccurred(), "failed to update DaemonSet status")
+
+			Eventually(func() int32 {
+				err := k8sClient.Get(ctx, myKindObjectKey, myKind)
+				Expect(err).NotTo(HaveOccurred(), "failed to get MyKind resource")
+				return myKind.Status.Replicas
+			}, time.Second*5, time.Millisecond*500).Should(Equal(int32(3)), "expected status to report the scheduled pods")
+			Expect(myKind.Status.WorkloadKind).To(Equal(mygroupv1.WorkloadKindDaemonSet))
+			Expect(myKind.Status.UnavailableReplicas).To(Equal(int32(1)))
+			Expect(getMyKindConditionStatus(myKind, mygroupv1.MyKindDeploymentAvailable)).To(Equal(core.ConditionFalse))
+			Expect(getMyKindConditionStatus(myKind, mygroupv1.MyKindReady)).To(Equal(core.ConditionFalse))
+		})
+
+		It("should keep the previous workload until the workload of the new kind has been rolled out", func() {
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, workloadObjectKey, &apps.StatefulSet{}),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "stateful set resource should exist")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.WorkloadKind = mygroupv1.WorkloadKindDeployment
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			deployment := &apps.Deployment{}
+			Eventually(
+				getResourceFunc(ctx, workloadObjectKey, deployment),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "deployment resource should exist")
+			Eventually(getMyKindStatusWorkloadKindFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(mygroupv1.WorkloadKindDeployment), "expected status to report the Deployment")
+
+			// The Deployment has not been rolled out, as there is no
+			// Deployment controller running in the test environment.
+			Consistently(
+				getResourceFunc(ctx, workloadObjectKey, &apps.StatefulSet{}),
+				time.Second*2, time.Millisecond*500).Should(BeNil(), "stateful set resource should not be deleted before the deployment is rolled out")
+
+			deployment.Status = apps.DeploymentStatus{
+				ObservedGeneration: deployment.Generation,
+				Replicas:           1,
+				UpdatedReplicas:    1,
+				ReadyReplicas:      1,
+				AvailableReplicas:  1,
+			}
+			err = k8sClient.Status().Update(ctx, deployment)
+			Expect(err).NotTo(HaveOccurred(), "failed to update Deployment status")
+
+			Eventually(
+				getResourceFunc(ctx, workloadObjectKey, &apps.StatefulSet{}),
+				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "stateful set resource should be deleted")
+			Expect(getResourceFunc(ctx, workloadObjectKey, &apps.Deployment{})()).To(BeNil(), "deployment resource should not be deleted")
+		})
+
+		It("should orphan the workloads of every kind if the deletion policy is Orphan", func() {
+			myKind.Spec.DeletionPolicy = mygroupv1.DeletionPolicyOrphan
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, workloadObjectKey, &apps.StatefulSet{}),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "stateful set resource should exist")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.WorkloadKind = mygroupv1.WorkloadKindDeployment
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, workloadObjectKey, &apps.Deployment{}),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "deployment resource should exist")
+
+			err = k8sClient.Delete(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to delete MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, myKindObjectKey, &mygroupv1.MyKind{}),
+				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "MyKind resource should be deleted")
+
+			statefulSet := &apps.StatefulSet{}
+			err = k8sClient.Get(ctx, workloadObjectKey, statefulSet)
+			Expect(err).NotTo(HaveOccurred(), "stateful set resource should not be deleted")
+			Expect(statefulSet.OwnerReferences).To(BeEmpty(), "expected owner reference to be removed from the stateful set")
+
+			deployment := &apps.Deployment{}
+			err = k8sClient.Get(ctx, workloadObjectKey, deployment)
+			Expect(err).NotTo(HaveOccurred(), "deployment resource should not be deleted")
+			Expect(deployment.OwnerReferences).To(BeEmpty(), "expected owner reference to be removed from the deployment")
+		})
+	})
+
+	Describe("when components are specified", func() {
+		var myKindObjectKey client.ObjectKey
+		var myKind *mygroupv1.MyKind
+
+		BeforeEach(func() {
+			myKindObjectKey = client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			myKind = &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: "web",
+					},
+					Components: []mygroupv1.ComponentSpec{
+						{
+							Name: "worker",
+							Deployment: mygroupv1.DeploymentSpec{
+								Name:      "worker",
+								Replicas:  pointer.Int32Ptr(2),
+								Container: mygroupv1.ContainerSpec{Image: "busybox"},
+							},
+						},
+						{
+							Name: "scheduler",
+							Deployment: mygroupv1.DeploymentSpec{
+								Name: "scheduler",
+							},
+						},
+					},
+				},
+			}
+		})
+
+		It("should create a Deployment for each component and report it in the status", func() {
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, client.ObjectKey{Namespace: ns.Name, Name: "web"}, &apps.Deployment{}),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "deployment resource should exist")
+
+			worker := &apps.Deployment{}
+			Eventually(
+				getResourceFunc(ctx, client.ObjectKey{Namespace: ns.Name, Name: "worker"}, worker),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "worker deployment resource should exist")
+			Expect(*worker.Spec.Replicas).To(Equal(int32(2)))
+			Expect(worker.Spec.Template.Spec.Containers[0].Image).To(Equal("busybox"))
+			Expect(worker.Labels).To(HaveKeyWithValue(mygroupv1.ComponentLabel, "worker"))
+			Expect(worker.Spec.Template.Labels).To(HaveKeyWithValue(mygroupv1.ComponentLabel, "worker"))
+			Expect(worker.Spec.Selector.MatchLabels).To(Equal(map[string]string{mygroupv1.DeploymentNameLabel: "worker"}))
+
+			Eventually(
+				getResourceFunc(ctx, client.ObjectKey{Namespace: ns.Name, Name: "scheduler"}, &apps.Deployment{}),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "scheduler deployment resource should exist")
+
+			Eventually(getMyKindComponentStatusesFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal([]mygroupv1.ComponentStatus{
+					{Name: "worker", DeploymentName: "worker", Available: core.ConditionUnknown},
+					{Name: "scheduler", DeploymentName: "scheduler", Available: core.ConditionUnknown},
+				}), "expected status to report each component")
+
+			worker.Status = apps.DeploymentStatus{
+				ObservedGeneration: worker.Generation,
+				Replicas:           2,
+				UpdatedReplicas:    2,
+				ReadyReplicas:      2,
+				AvailableReplicas:  2,
+				Conditions: []apps.DeploymentCondition{
+					{Type: apps.DeploymentAvailable, Status: core.ConditionTrue, Reason: "MinimumReplicasAvailable"},
+				},
+			}
+			err = k8sClient.Status().Update(ctx, worker)
+			Expect(err).NotTo(HaveOccurred(), "failed to update Deployment status")
+
+			Eventually(getMyKindComponentStatusesFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(ContainElement(mygroupv1.ComponentStatus{
+					Name:              "worker",
+					DeploymentName:    "worker",
+					Replicas:          2,
+					ReadyReplicas:     2,
+					UpdatedReplicas:   2,
+					AvailableReplicas: 2,
+					Available:         core.ConditionTrue,
+				}), "expected status to report the state of the worker Deployment")
+		})
+
+		It("should delete the Deployment of a component that is removed", func() {
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			schedulerObjectKey := client.ObjectKey{Namespace: ns.Name, Name: "scheduler"}
+			Eventually(
+				getResourceFunc(ctx, schedulerObjectKey, &apps.Deployment{}),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "scheduler deployment resource should exist")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.Components = myKind.Spec.Components[:1]
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, schedulerObjectKey, &apps.Deployment{}),
+				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "scheduler deployment resource should be deleted")
+			Expect(getResourceFunc(ctx, client.ObjectKey{Namespace: ns.Name, Name: "worker"}, &apps.Deployment{})()).
+				To(BeNil(), "worker deployment resource should not be deleted")
+			Expect(getResourceFunc(ctx, client.ObjectKey{Namespace: ns.Name, Name: "web"}, &apps.Deployment{})()).
+				To(BeNil(), "deployment resource should not be deleted")
+
+			Eventually(getMyKindComponentStatusesFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(HaveLen(1), "expected status to only report the remaining component")
+		})
+
+		It("should roll out the Deployment of a component when a ConfigMap only it references changes", func() {
+			configMap := &core.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "worker-config",
+					Namespace: ns.Name,
+				},
+				Data: map[string]string{"key": "value"},
+			}
+			err := k8sClient.Create(ctx, configMap)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test ConfigMap resource")
+
+			myKind.Spec.Components[0].Deployment.ConfigMaps = []mygroupv1.ConfigReference{{Name: configMap.Name}}
+			err = k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			workerObjectKey := client.ObjectKey{Namespace: ns.Name, Name: "worker"}
+			Eventually(getDeploymentConfigHashFunc(ctx, workerObjectKey), time.Second*5, time.Millisecond*500).
+				ShouldNot(BeEmpty(), "expected worker pod template to have a config hash")
+			oldHash := getDeploymentConfigHashFunc(ctx, workerObjectKey)()
+
+			configMap.Data["key"] = "new-value"
+			err = k8sClient.Update(ctx, configMap)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update ConfigMap resource")
+
+			Eventually(getDeploymentConfigHashFunc(ctx, workerObjectKey), time.Second*5, time.Millisecond*500).
+				ShouldNot(Equal(oldHash), "expected worker config hash to change when the ConfigMap is updated")
+		})
+	})
+
+	Describe("when a canary rollout is specified", func() {
+		var myKindObjectKey, stableObjectKey, canaryObjectKey client.ObjectKey
+		var myKind *mygroupv1.MyKind
+
+		BeforeEach(func() {
+			myKindObjectKey = client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			stableObjectKey = client.ObjectKey{Namespace: ns.Name, Name: "web"}
+			canaryObjectKey = client.ObjectKey{Namespace: ns.Name, Name: "web" + mygroupv1.CanaryDeploymentSuffix}
+			myKind = &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name:     "web",
+						Replicas: pointer.Int32Ptr(4),
+					},
+					Rollout: &mygroupv1.RolloutSpec{
+						Canary: &mygroupv1.CanaryStrategy{
+							Steps: []mygroupv1.CanaryStep{
+								{Weight: pointer.Int32Ptr(25), ManualPromotion: true},
+							},
+						},
+					},
+				},
+			}
+		})
+
+		// startCanaryRollout creates the MyKind, waits for its Deployment
+		// to be created and marks it as rolled out, and then changes the
+		// image so that a canary rollout is started.
+		startCanaryRollout := func() {
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, stableObjectKey, &apps.Deployment{}),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "deployment resource should exist")
+			markDeploymentRolledOut(ctx, stableObjectKey)
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.Deployment.Container.Image = "nginx:1.17"
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, canaryObjectKey, &apps.Deployment{}),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "canary deployment resource should exist")
+		}
+
+		It("should run the new pod template in a canary Deployment until it is promoted", func() {
+			startCanaryRollout()
+
+			Expect(getDeploymentImageFunc(ctx, canaryObjectKey)()).To(Equal("nginx:1.17"))
+			Expect(getDeploymentReplicasFunc(ctx, canaryObjectKey)()).To(Equal(int32(1)))
+			Eventually(getDeploymentReplicasFunc(ctx, stableObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(int32(3)), "expected the stable deployment to run the rest of the replicas")
+			Expect(getDeploymentImageFunc(ctx, stableObjectKey)()).To(Equal(mygroupv1.DefaultContainerImage))
+
+			markDeploymentRolledOut(ctx, canaryObjectKey)
+			Eventually(getMyKindCanaryPhaseFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(mygroupv1.CanaryPhaseAwaitingPromotion), "expected the rollout to await promotion")
+			Consistently(getDeploymentImageFunc(ctx, stableObjectKey), time.Second*2, time.Millisecond*500).
+				Should(Equal(mygroupv1.DefaultContainerImage), "stable deployment should not be updated before promotion")
+
+			err := k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Annotations = map[string]string{mygroupv1.PromoteAnnotation: "true"}
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(getDeploymentImageFunc(ctx, stableObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal("nginx:1.17"), "expected the stable deployment to be promoted")
+			Eventually(getDeploymentReplicasFunc(ctx, stableObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(int32(4)), "expected the stable deployment to run all of the replicas")
+			Eventually(getMyKindCanaryPhaseFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(mygroupv1.CanaryPhasePromoting))
+			Expect(getResourceFunc(ctx, canaryObjectKey, &apps.Deployment{})()).
+				To(BeNil(), "canary deployment resource should not be deleted before the stable deployment is rolled out")
+
+			markDeploymentRolledOut(ctx, stableObjectKey)
+			Eventually(
+				getResourceFunc(ctx, canaryObjectKey, &apps.Deployment{}),
+				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "canary deployment resource should be deleted")
+			Eventually(getMyKindCanaryPhaseFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(BeEmpty(), "expected the canary status to be removed")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			Expect(myKind.Annotations).NotTo(HaveKey(mygroupv1.PromoteAnnotation), "expected the promote annotation to be removed")
+		})
+
+		It("should return all replicas to the stable Deployment when the rollout is aborted", func() {
+			startCanaryRollout()
+
+			err := k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.Rollout.Canary.Abort = true
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, canaryObjectKey, &apps.Deployment{}),
+				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "canary deployment resource should be deleted")
+			Eventually(getDeploymentReplicasFunc(ctx, stableObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(int32(4)), "expected the stable deployment to run all of the replicas")
+			Expect(getDeploymentImageFunc(ctx, stableObjectKey)()).To(Equal(mygroupv1.DefaultContainerImage))
+			Eventually(getMyKindCanaryPhaseFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(mygroupv1.CanaryPhaseAborted))
+			Eventually(getMyKindConditionStatusFunc(ctx, myKindObjectKey, mygroupv1.MyKindDegraded), time.Second*5, time.Millisecond*500).
+				Should(Equal(core.ConditionTrue), "expected an aborted rollout to be reported as degraded")
+		})
+	})
+
+	Describe("when a blue/green rollout is specified", func() {
+		var myKindObjectKey, blueObjectKey, greenObjectKey, serviceObjectKey, previewServiceObjectKey client.ObjectKey
+		var myKind *mygroupv1.MyKind
+
+		BeforeEach(func() {
+			myKindObjectKey = client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			blueObjectKey = client.ObjectKey{Namespace: ns.Name, Name: "web-blue"}
+			greenObjectKey = client.ObjectKey{Namespace: ns.Name, Name: "web-green"}
+			serviceObjectKey = client.ObjectKey{Namespace: ns.Name, Name: "web"}
+			previewServiceObjectKey = client.ObjectKey{Namespace: ns.Name, Name: "web" + mygroupv1.PreviewServiceSuffix}
+			myKind = &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name:     "web",
+						Replicas: pointer.Int32Ptr(2),
+					},
+					Service: &mygroupv1.ServiceSpec{
+						Ports: []core.ServicePort{{Name: "http", Port: 80}},
+					},
+					Rollout: &mygroupv1.RolloutSpec{
+						BlueGreen: &mygroupv1.BlueGreenStrategy{
+							ScaleDownDelay: &metav1.Duration{Duration: time.Second},
+						},
+					},
+				},
+			}
+		})
+
+		It("should switch the Service to the preview Deployment when it is promoted", func() {
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, blueObjectKey, &apps.Deployment{}),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "blue deployment resource should exist")
+			markDeploymentRolledOut(ctx, blueObjectKey)
+			Eventually(getServiceSelectorColorFunc(ctx, serviceObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal("blue"), "expected the service to route traffic to the blue deployment")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.Deployment.Container.Image = "nginx:1.17"
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(
+				getResourceFunc(ctx, greenObjectKey, &apps.Deployment{}),
+				time.Second*5, time.Millisecond*500).Should(BeNil(), "green deployment resource should exist")
+			Expect(getDeploymentImageFunc(ctx, greenObjectKey)()).To(Equal("nginx:1.17"))
+			Expect(getDeploymentImageFunc(ctx, blueObjectKey)()).To(Equal(mygroupv1.DefaultContainerImage))
+			Eventually(getServiceSelectorColorFunc(ctx, previewServiceObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal("green"), "expected the preview service to route traffic to the green deployment")
+
+			markDeploymentRolledOut(ctx, greenObjectKey)
+			Eventually(getMyKindConditionReasonFunc(ctx, myKindObjectKey, mygroupv1.MyKindProgressing), time.Second*5, time.Millisecond*500).
+				Should(Equal("BlueGreenAwaitingPromotion"))
+			Consistently(getServiceSelectorColorFunc(ctx, serviceObjectKey), time.Second*2, time.Millisecond*500).
+				Should(Equal("blue"), "service should not be switched before promotion")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Annotations = map[string]string{mygroupv1.PromoteAnnotation: "true"}
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(getServiceSelectorColorFunc(ctx, serviceObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal("green"), "expected the service to be switched to the green deployment")
+			Eventually(
+				getResourceFunc(ctx, blueObjectKey, &apps.Deployment{}),
+				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "blue deployment resource should be deleted after the scale down delay")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			Expect(myKind.Annotations).NotTo(HaveKey(mygroupv1.PromoteAnnotation), "expected the promote annotation to be removed")
+			Expect(myKind.Status.DeploymentName).To(Equal(greenObjectKey.Name))
+		})
+	})
+
+	Describe("when the spec of a MyKind is changed", func() {
+		var myKindObjectKey, deploymentObjectKey client.ObjectKey
+		var myKind *mygroupv1.MyKind
+
+		BeforeEach(func() {
+			myKindObjectKey = client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			deploymentObjectKey = client.ObjectKey{Namespace: ns.Name, Name: "web"}
+			myKind = &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: "web",
+					},
+					RevisionHistoryLimit: pointer.Int32Ptr(1),
+				},
+			}
+		})
+
+		// updateImage sets the container image of the MyKind and waits for
+		// it to be recorded in a new revision.
+		updateImage := func(image string) {
+			previous := getMyKindCurrentRevisionFunc(ctx, myKindObjectKey)()
+
+			err := k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.Deployment.Container.Image = image
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(getMyKindCurrentRevisionFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				ShouldNot(Equal(previous), "expected the new spec to be recorded in a new revision")
+		}
+
+		It("should record a bounded history of revisions and roll back to the previous one", func() {
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(getMyKindCurrentRevisionFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				ShouldNot(BeEmpty(), "expected the spec to be recorded in a revision")
+			updateImage("nginx:1.16")
+			updateImage("nginx:1.17")
+
+			revisions := &apps.ControllerRevisionList{}
+			err = k8sClient.List(ctx, revisions, client.InNamespace(ns.Name))
+			Expect(err).NotTo(HaveOccurred(), "failed to list ControllerRevision resources")
+			Expect(revisions.Items).To(HaveLen(2), "expected the oldest revision to be deleted")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.RollbackTo = &mygroupv1.RollbackConfig{}
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(getDeploymentImageFunc(ctx, deploymentObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal("nginx:1.16"), "expected the deployment to be rolled back")
+			Eventually(getMyKindEventMessagesFunc(ctx, myKindObjectKey, "RolledBack"), time.Second*5, time.Millisecond*500).
+				Should(ContainElement("Rolled back to revision 2"))
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			Expect(myKind.Spec.RollbackTo).To(BeNil(), "expected spec.rollbackTo to be removed")
+			Expect(myKind.Spec.Deployment.Container.Image).To(Equal("nginx:1.16"))
+		})
+	})
+
+	Describe("when a new pod template of a MyKind fails to roll out", func() {
+		var myKindObjectKey, deploymentObjectKey client.ObjectKey
+		var myKind *mygroupv1.MyKind
+
+		BeforeEach(func() {
+			myKindObjectKey = client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			deploymentObjectKey = client.ObjectKey{Namespace: ns.Name, Name: "web"}
+			myKind = &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name:      "web",
+						Container: mygroupv1.ContainerSpec{Image: "nginx:1.16"},
+					},
+					HealthCheck: &mygroupv1.HealthCheckSpec{AutoRollback: true},
+				},
+			}
+		})
+
+		It("should mark the MyKind as Degraded and roll back to the last known good revision", func() {
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(getDeploymentImageFunc(ctx, deploymentObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal("nginx:1.16"), "expected the deployment to be created")
+			markDeploymentProgress(ctx, deploymentObjectKey, "NewReplicaSetAvailable")
+			Eventually(getMyKindLastKnownGoodRevisionFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				ShouldNot(BeEmpty(), "expected the rolled out revision to be known good")
+
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Spec.Deployment.Container.Image = "nginx:1.17"
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(getDeploymentImageFunc(ctx, deploymentObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal("nginx:1.17"), "expected the new pod template to be rolled out")
+			markDeploymentProgress(ctx, deploymentObjectKey, "ProgressDeadlineExceeded")
+
+			Eventually(getMyKindConditionReasonFunc(ctx, myKindObjectKey, mygroupv1.MyKindDegraded), time.Second*5, time.Millisecond*500).
+				Should(Equal("ProgressDeadlineExceeded"), "expected the MyKind to be Degraded")
+			Eventually(getDeploymentImageFunc(ctx, deploymentObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal("nginx:1.16"), "expected the deployment to be rolled back")
+			Eventually(getMyKindEventMessagesFunc(ctx, myKindObjectKey, "HealthCheckFailed"), time.Second*5, time.Millisecond*500).
+				ShouldNot(BeEmpty(), "expected the failed health check to be recorded")
+			Eventually(getMyKindEventMessagesFunc(ctx, myKindObjectKey, "AutoRollback"), time.Second*5, time.Millisecond*500).
+				Should(HaveLen(1), "expected the rollback to be recorded")
+		})
+	})
+})
+
+var _ = Context("Inside of a new namespace without forced ownership", func() {
+	ctx := context.TODO()
+	ns := SetupTest(ctx, func(r *MyKindReconciler) { r.ForceOwnership = false })
+
+	Describe("when another field manager modifies a Deployment", func() {
+		It("should not override fields set by another field manager", func() {
+			deploymentObjectKey := client.ObjectKey{
+				Name:      "deployment-name",
 				Namespace: ns.Name,
 			}
 			myKindObjectKey := client.ObjectKey{
 				Name:      "testresource",
 				Namespace: ns.Name,
 			}
-			myKind := &mygroupv1beta1.MyKind{
+			myKind := &mygroupv1.MyKind{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      myKindObjectKey.Name,
 					Namespace: myKindObjectKey.Namespace,
 				},
-				Spec: mygroupv1beta1.MyKindSpec{
-					DeploymentName: deploymentObjectKey.Name,
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name: deploymentObjectKey.Name,
+					},
 				},
 			}
 
@@ -153,20 +1948,82 @@ var _ = Context("Inside of a new namespace", func() {
 				getResourceFunc(ctx, deploymentObjectKey, deployment),
 				time.Second*5, time.Millisecond*500).Should(BeNil(), "deployment resource should exist")
 
+			deployment.Spec.Template.Spec.Containers[0].Image = "other:1"
+			err = k8sClient.Update(ctx, deployment)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update Deployment resource")
+
 			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
 			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
-
-			myKind.Spec.DeploymentName = newDeploymentObjectKey.Name
+			myKind.Spec.Deployment.Container.Image = "nginx:1.17"
 			err = k8sClient.Update(ctx, myKind)
 			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
 
-			Eventually(
-				getResourceFunc(ctx, deploymentObjectKey, deployment),
-				time.Second*5, time.Millisecond*500).ShouldNot(BeNil(), "old deployment resource should be deleted")
+			Eventually(getMyKindConditionStatusFunc(ctx, myKindObjectKey, mygroupv1.MyKindDegraded), time.Second*5, time.Millisecond*500).
+				Should(Equal(core.ConditionTrue), "expected the field conflict to be reported as Degraded")
+			Consistently(getDeploymentImageFunc(ctx, deploymentObjectKey), time.Second*2, time.Millisecond*500).
+				Should(Equal("other:1"), "expected the Deployment image set by another field manager to be kept")
+		})
+	})
+})
 
-			Eventually(
-				getResourceFunc(ctx, newDeploymentObjectKey, deployment),
-				time.Second*5, time.Millisecond*500).Should(BeNil(), "new deployment resource should be created")
+var _ = Context("Inside of a new namespace with a fake clock", func() {
+	ctx := context.TODO()
+	// 21:00 on a Wednesday in London, after the overnight schedule below
+	// has started.
+	fakeClock := clock.NewFakeClock(time.Date(2019, time.June, 5, 20, 0, 0, 0, time.UTC))
+	ns := SetupTest(ctx, func(r *MyKindReconciler) { r.Clock = fakeClock })
+
+	Describe("when a MyKind has scaling schedules", func() {
+		It("should scale the workload while a schedule is active", func() {
+			deploymentObjectKey := client.ObjectKey{
+				Name:      "deployment-name",
+				Namespace: ns.Name,
+			}
+			myKindObjectKey := client.ObjectKey{
+				Name:      "testresource",
+				Namespace: ns.Name,
+			}
+			myKind := &mygroupv1.MyKind{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      myKindObjectKey.Name,
+					Namespace: myKindObjectKey.Namespace,
+				},
+				Spec: mygroupv1.MyKindSpec{
+					Deployment: mygroupv1.DeploymentSpec{
+						Name:     deploymentObjectKey.Name,
+						Replicas: pointer.Int32Ptr(2),
+					},
+					Schedules: []mygroupv1.ScaleSchedule{
+						{Name: "weekend", Start: "0 20 * * fri", End: "0 8 * * mon", TimeZone: "Europe/London"},
+						{Name: "overnight", Start: "0 20 * * *", End: "0 8 * * *", TimeZone: "Europe/London"},
+					},
+				},
+			}
+
+			err := k8sClient.Create(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to create test MyKind resource")
+
+			Eventually(getMyKindActiveScheduleFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal("overnight"), "expected the overnight schedule to be active")
+			Expect(getDeploymentReplicasFunc(ctx, deploymentObjectKey)()).To(Equal(int32(0)), "expected the deployment to be scaled to zero")
+
+			// 08:00 in London, when the schedule ends, but not yet in UTC.
+			// The controller would be woken up at the end of the schedule,
+			// but the fake clock does not wake it, so the MyKind is modified
+			// to trigger a reconcile instead.
+			fakeClock.SetTime(time.Date(2019, time.June, 6, 7, 0, 0, 0, time.UTC))
+			err = k8sClient.Get(ctx, myKindObjectKey, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to retrieve MyKind resource")
+			myKind.Annotations = map[string]string{"example.com/touch": "1"}
+			err = k8sClient.Update(ctx, myKind)
+			Expect(err).NotTo(HaveOccurred(), "failed to Update MyKind resource")
+
+			Eventually(getDeploymentReplicasFunc(ctx, deploymentObjectKey), time.Second*5, time.Millisecond*500).
+				Should(Equal(int32(2)), "expected the deployment to be scaled back up")
+			Eventually(getMyKindActiveScheduleFunc(ctx, myKindObjectKey), time.Second*5, time.Millisecond*500).
+				Should(BeEmpty(), "expected no schedule to be active")
+			Eventually(getMyKindEventMessagesFunc(ctx, myKindObjectKey, "ScheduleEnded"), time.Second*5, time.Millisecond*500).
+				Should(ContainElement(`Scaled back to 2 replicas as schedule "overnight" has ended`))
 		})
 	})
 })
@@ -186,3 +2043,304 @@ func getDeploymentReplicasFunc(ctx context.Context, key client.ObjectKey) func()
 		return *depl.Spec.Replicas
 	}
 }
+
+func getDeploymentImageFunc(ctx context.Context, key client.ObjectKey) func() string {
+	return func() string {
+		depl := &apps.Deployment{}
+		err := k8sClient.Get(ctx, key, depl)
+		Expect(err).NotTo(HaveOccurred(), "failed to get Deployment resource")
+
+		return depl.Spec.Template.Spec.Containers[0].Image
+	}
+}
+
+func getDeploymentEnvFunc(ctx context.Context, key client.ObjectKey, name string) func() string {
+	return func() string {
+		depl := &apps.Deployment{}
+		err := k8sClient.Get(ctx, key, depl)
+		Expect(err).NotTo(HaveOccurred(), "failed to get Deployment resource")
+
+		for _, env := range depl.Spec.Template.Spec.Containers[0].Env {
+			if env.Name == name {
+				return env.Value
+			}
+		}
+		return ""
+	}
+}
+
+func getDeploymentConfigHashFunc(ctx context.Context, key client.ObjectKey) func() string {
+	return func() string {
+		depl := &apps.Deployment{}
+		err := k8sClient.Get(ctx, key, depl)
+		if err != nil {
+			return ""
+		}
+
+		return depl.Spec.Template.Annotations[configHashAnnotation]
+	}
+}
+
+func getDeploymentControllerNameFunc(ctx context.Context, key client.ObjectKey) func() string {
+	return func() string {
+		depl := &apps.Deployment{}
+		err := k8sClient.Get(ctx, key, depl)
+		Expect(err).NotTo(HaveOccurred(), "failed to get Deployment resource")
+
+		if owner := metav1.GetControllerOf(depl); owner != nil {
+			return owner.Name
+		}
+		return ""
+	}
+}
+
+func getServicePortFunc(ctx context.Context, key client.ObjectKey) func() int32 {
+	return func() int32 {
+		svc := &core.Service{}
+		err := k8sClient.Get(ctx, key, svc)
+		Expect(err).NotTo(HaveOccurred(), "failed to get Service resource")
+
+		return svc.Spec.Ports[0].Port
+	}
+}
+
+func getMyKindObservedGenerationFunc(ctx context.Context, key client.ObjectKey) func() int64 {
+	return func() int64 {
+		myKind := &mygroupv1.MyKind{}
+		err := k8sClient.Get(ctx, key, myKind)
+		Expect(err).NotTo(HaveOccurred(), "failed to get MyKind resource")
+
+		return myKind.Status.ObservedGeneration
+	}
+}
+
+func getMyKindStatusDeploymentNameFunc(ctx context.Context, key client.ObjectKey) func() string {
+	return func() string {
+		myKind := &mygroupv1.MyKind{}
+		err := k8sClient.Get(ctx, key, myKind)
+		Expect(err).NotTo(HaveOccurred(), "failed to get MyKind resource")
+
+		return myKind.Status.DeploymentName
+	}
+}
+
+func getMyKindStatusWorkloadKindFunc(ctx context.Context, key client.ObjectKey) func() mygroupv1.WorkloadKind {
+	return func() mygroupv1.WorkloadKind {
+		myKind := &mygroupv1.MyKind{}
+		err := k8sClient.Get(ctx, key, myKind)
+		Expect(err).NotTo(HaveOccurred(), "failed to get MyKind resource")
+
+		return myKind.Status.WorkloadKind
+	}
+}
+
+func getMyKindComponentStatusesFunc(ctx context.Context, key client.ObjectKey) func() []mygroupv1.ComponentStatus {
+	return func() []mygroupv1.ComponentStatus {
+		myKind := &mygroupv1.MyKind{}
+		err := k8sClient.Get(ctx, key, myKind)
+		Expect(err).NotTo(HaveOccurred(), "failed to get MyKind resource")
+
+		return myKind.Status.Components
+	}
+}
+
+func getMyKindCanaryPhaseFunc(ctx context.Context, key client.ObjectKey) func() mygroupv1.CanaryPhase {
+	return func() mygroupv1.CanaryPhase {
+		myKind := &mygroupv1.MyKind{}
+		err := k8sClient.Get(ctx, key, myKind)
+		Expect(err).NotTo(HaveOccurred(), "failed to get MyKind resource")
+
+		if myKind.Status.Canary == nil {
+			return ""
+		}
+		return myKind.Status.Canary.Phase
+	}
+}
+
+func getServiceSelectorColorFunc(ctx context.Context, key client.ObjectKey) func() string {
+	return func() string {
+		svc := &core.Service{}
+		err := k8sClient.Get(ctx, key, svc)
+		Expect(err).NotTo(HaveOccurred(), "failed to get Service resource")
+
+		return svc.Spec.Selector[mygroupv1.ColorLabel]
+	}
+}
+
+func getMyKindCurrentRevisionFunc(ctx context.Context, key client.ObjectKey) func() string {
+	return func() string {
+		myKind := &mygroupv1.MyKind{}
+		err := k8sClient.Get(ctx, key, myKind)
+		Expect(err).NotTo(HaveOccurred(), "failed to get MyKind resource")
+
+		return myKind.Status.CurrentRevision
+	}
+}
+
+func getMyKindFinalizersFunc(ctx context.Context, key client.ObjectKey) func() []string {
+	return func() []string {
+		myKind := &mygroupv1.MyKind{}
+		err := k8sClient.Get(ctx, key, myKind)
+		Expect(err).NotTo(HaveOccurred(), "failed to get MyKind resource")
+
+		return myKind.Finalizers
+	}
+}
+
+func getMyKindActiveScheduleFunc(ctx context.Context, key client.ObjectKey) func() string {
+	return func() string {
+		myKind := &mygroupv1.MyKind{}
+		err := k8sClient.Get(ctx, key, myKind)
+		Expect(err).NotTo(HaveOccurred(), "failed to get MyKind resource")
+
+		return myKind.Status.ActiveSchedule
+	}
+}
+
+func getMyKindLastKnownGoodRevisionFunc(ctx context.Context, key client.ObjectKey) func() string {
+	return func() string {
+		myKind := &mygroupv1.MyKind{}
+		err := k8sClient.Get(ctx, key, myKind)
+		Expect(err).NotTo(HaveOccurred(), "failed to get MyKind resource")
+
+		return myKind.Status.LastKnownGoodRevision
+	}
+}
+
+func getMyKindEventMessagesFunc(ctx context.Context, key client.ObjectKey, reason string) func() []string {
+	return func() []string {
+		events := &core.EventList{}
+		err := k8sClient.List(ctx, events, client.InNamespace(key.Namespace))
+		Expect(err).NotTo(HaveOccurred(), "failed to list Event resources")
+
+		var messages []string
+		for _, event := range events.Items {
+			if event.InvolvedObject.Kind == "MyKind" && event.InvolvedObject.Name == key.Name && event.Reason == reason {
+				messages = append(messages, event.Message)
+			}
+		}
+		return messages
+	}
+}
+
+func getMyKindConditionStatusFunc(ctx context.Context, key client.ObjectKey, condType mygroupv1.MyKindConditionType) func() core.ConditionStatus {
+	return func() core.ConditionStatus {
+		myKind := &mygroupv1.MyKind{}
+		err := k8sClient.Get(ctx, key, myKind)
+		Expect(err).NotTo(HaveOccurred(), "failed to get MyKind resource")
+
+		return getMyKindConditionStatus(myKind, condType)
+	}
+}
+
+func getMyKindConditionReasonFunc(ctx context.Context, key client.ObjectKey, condType mygroupv1.MyKindConditionType) func() string {
+	return func() string {
+		myKind := &mygroupv1.MyKind{}
+		err := k8sClient.Get(ctx, key, myKind)
+		Expect(err).NotTo(HaveOccurred(), "failed to get MyKind resource")
+
+		for _, c := range myKind.Status.Conditions {
+			if c.Type == condType {
+				return c.Reason
+			}
+		}
+		return ""
+	}
+}
+
+func getMyKindConditionStatus(myKind *mygroupv1.MyKind, condType mygroupv1.MyKindConditionType) core.ConditionStatus {
+	for _, c := range myKind.Status.Conditions {
+		if c.Type == condType {
+			return c.Status
+		}
+	}
+	return ""
+}
+
+// markDeploymentRolledOut sets the status of the Deployment with the given
+// key as the Deployment controller would once all of its replicas are
+// updated and available, as there is no Deployment controller running in the
+// test environment.
+func markDeploymentRolledOut(ctx context.Context, key client.ObjectKey) {
+	deployment := &apps.Deployment{}
+	err := k8sClient.Get(ctx, key, deployment)
+	Expect(err).NotTo(HaveOccurred(), "failed to get Deployment")
+
+	replicas := *deployment.Spec.Replicas
+	deployment.Status = apps.DeploymentStatus{
+		ObservedGeneration: deployment.Generation,
+		Replicas:           replicas,
+		UpdatedReplicas:    replicas,
+		ReadyReplicas:      replicas,
+		AvailableReplicas:  replicas,
+	}
+	err = k8sClient.Status().Update(ctx, deployment)
+	Expect(err).NotTo(HaveOccurred(), "failed to update Deployment status")
+}
+
+func myKindScaleRequest(method string, key client.ObjectKey) (*rest.Request, error) {
+	restClient, err := apiutil.RESTClientForGVK(autoscaling.SchemeGroupVersion.WithKind("Scale"), cfg, serializer.NewCodecFactory(scheme.Scheme))
+	if err != nil {
+		return nil, err
+	}
+
+	return restClient.Verb(method).AbsPath("/apis", mygroupv1.GroupVersion.Group, mygroupv1.GroupVersion.Version,
+		"namespaces", key.Namespace, "mykinds", key.Name, "scale"), nil
+}
+
+func getMyKindScale(key client.ObjectKey) (*autoscaling.Scale, error) {
+	req, err := myKindScaleRequest("GET", key)
+	if err != nil {
+		return nil, err
+	}
+
+	scale := &autoscaling.Scale{}
+	if err := req.Do().Into(scale); err != nil {
+		return nil, err
+	}
+	return scale, nil
+}
+
+func updateMyKindScale(key client.ObjectKey, replicas int32) error {
+	scale, err := getMyKindScale(key)
+	if err != nil {
+		return err
+	}
+
+	req, err := myKindScaleRequest("PUT", key)
+	if err != nil {
+		return err
+	}
+
+	scale.Spec.Replicas = replicas
+	return req.Body(scale).Do().Error()
+}
+
+// markDeploymentProgress sets the status of the Deployment with the given
+// key as the Deployment controller would once all of its replicas are
+// available, with its Progressing condition set to the given reason, as
+// there is no Deployment controller running in the test environment.
+func markDeploymentProgress(ctx context.Context, key client.ObjectKey, reason string) {
+	deployment := &apps.Deployment{}
+	err := k8sClient.Get(ctx, key, deployment)
+	Expect(err).NotTo(HaveOccurred(), "failed to get Deployment")
+
+	progressing := core.ConditionTrue
+	if reason == "ProgressDeadlineExceeded" {
+		progressing = core.ConditionFalse
+	}
+	replicas := *deployment.Spec.Replicas
+	deployment.Status = apps.DeploymentStatus{
+		ObservedGeneration: deployment.Generation,
+		Replicas:           replicas,
+		UpdatedReplicas:    replicas,
+		ReadyReplicas:      replicas,
+		AvailableReplicas:  replicas,
+		Conditions: []apps.DeploymentCondition{
+			{Type: apps.DeploymentAvailable, Status: core.ConditionTrue, Reason: "MinimumReplicasAvailable"},
+			{Type: apps.DeploymentProgressing, Status: progressing, Reason: reason},
+		},
+	}
+	err = k8sClient.Status().Update(ctx, deployment)
+	Expect(err).NotTo(HaveOccurred(), "failed to update Deployment status")
+}
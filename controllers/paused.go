@@ -0,0 +1,49 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	core "k8s.io/api/core/v1"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// pauseReason returns the reason that reconciliation of the given MyKind is
+// paused, or an empty string if it is not paused.
+func pauseReason(myKind mygroupv1.MyKind) string {
+	if myKind.Spec.Paused {
+		return "SpecPaused"
+	}
+	if myKind.Annotations[mygroupv1.PausedAnnotation] == "true" {
+		return "PausedAnnotation"
+	}
+	return ""
+}
+
+// recordPauseTransition emits an event if the given MyKind has been paused
+// or resumed since its status was last updated.
+func (r *MyKindReconciler) recordPauseTransition(myKind *mygroupv1.MyKind) {
+	wasPaused := conditionStatus(myKind.Status.Conditions, mygroupv1.MyKindPaused) == core.ConditionTrue
+	reason := pauseReason(*myKind)
+
+	switch {
+	case reason != "" && !wasPaused:
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Paused", "Paused reconciliation (%s)", reason)
+	case reason == "" && wasPaused:
+		r.Recorder.Event(myKind, core.EventTypeNormal, "Resumed", "Resumed reconciliation")
+	}
+}
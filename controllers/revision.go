@@ -0,0 +1,287 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// revisionData is the data recorded in each ControllerRevision of a MyKind.
+type revisionData struct {
+	Spec mygroupv1.MyKindSpec `json:"spec"`
+}
+
+// reconcileRevisions ensures that the spec of the given MyKind is recorded
+// in a ControllerRevision numbered after all of its other revisions, and
+// deletes the oldest revisions beyond spec.revisionHistoryLimit.
+// A spec that is the same as that of a previous revision, such as after a
+// rollback, is recorded by renumbering that revision.
+// It returns the name of the revision of the current spec.
+func (r *MyKindReconciler) reconcileRevisions(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) (string, error) {
+	desired, err := buildRevision(*myKind)
+	if err != nil {
+		log.Error(err, "failed to build ControllerRevision for MyKind resource")
+		return "", err
+	}
+	log = log.WithValues("revision_name", desired.Name)
+
+	revisions, err := r.listOwnedRevisions(ctx, myKind)
+	if err != nil {
+		log.Error(err, "failed to list ControllerRevisions for MyKind resource")
+		return "", err
+	}
+
+	var current *apps.ControllerRevision
+	latest := int64(0)
+	for i := range revisions {
+		if revisions[i].Name == desired.Name {
+			current = &revisions[i]
+		}
+		if revisions[i].Revision > latest {
+			latest = revisions[i].Revision
+		}
+	}
+
+	switch {
+	case current == nil:
+		log.Info("recording spec in a new ControllerRevision", "revision", latest+1)
+		desired.Revision = latest + 1
+		if err := r.apply(ctx, desired); err != nil {
+			log.Error(err, "failed to create ControllerRevision")
+			return "", err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Created", "Created controller revision %q", desired.Name)
+		revisions = append(revisions, *desired)
+	case current.Revision != latest:
+		log.Info("spec matches a previous ControllerRevision, renumbering it", "old_revision", current.Revision, "new_revision", latest+1)
+		base := current.DeepCopy()
+		current.Revision = latest + 1
+		if err := r.patch(ctx, current, base); err != nil {
+			log.Error(err, "failed to renumber ControllerRevision")
+			return "", err
+		}
+	default:
+		log.Info("spec is recorded in the latest ControllerRevision", "revision", current.Revision)
+	}
+
+	if err := r.truncateRevisions(ctx, log, myKind, revisions, desired.Name, myKind.Status.LastKnownGoodRevision); err != nil {
+		log.Error(err, "failed to delete old ControllerRevisions")
+		return "", err
+	}
+
+	return desired.Name, nil
+}
+
+// truncateRevisions deletes the oldest of the given revisions of the given
+// MyKind, other than the current and last known good ones with the given
+// names, until there are no more than spec.revisionHistoryLimit of them in
+// addition to the current one.
+func (r *MyKindReconciler) truncateRevisions(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, revisions []apps.ControllerRevision, current, lastKnownGood string) error {
+	limit := mygroupv1.DefaultRevisionHistoryLimit
+	if myKind.Spec.RevisionHistoryLimit != nil {
+		limit = int(*myKind.Spec.RevisionHistoryLimit)
+	}
+
+	sortRevisions(revisions)
+	excess := len(revisions) - 1 - limit
+	for i := 0; i < len(revisions) && excess > 0; i++ {
+		if revisions[i].Name == current || revisions[i].Name == lastKnownGood {
+			continue
+		}
+		log.Info("deleting old ControllerRevision", "name", revisions[i].Name, "revision", revisions[i].Revision)
+		if err := r.Client.Delete(ctx, &revisions[i]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		excess--
+	}
+
+	return nil
+}
+
+// rollback restores the spec of the given MyKind from the revision
+// requested by spec.rollbackTo or the RollbackToAnnotation, and removes the
+// request, so that the restored spec is reconciled by the reconcile
+// triggered by the update. A request for a revision that does not exist is
+// removed without changing anything else.
+func (r *MyKindReconciler) rollback(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) error {
+	target, err := rollbackRevision(*myKind)
+	if err != nil {
+		r.Recorder.Eventf(myKind, core.EventTypeWarning, "RollbackFailed", "Unable to roll back: %v", err)
+		log.Info("ignoring invalid rollback request", "reason", err.Error())
+		return r.restoreRevision(ctx, log, myKind, nil)
+	}
+	log = log.WithValues("target_revision", target)
+
+	revisions, err := r.listOwnedRevisions(ctx, myKind)
+	if err != nil {
+		log.Error(err, "failed to list ControllerRevisions for MyKind resource")
+		return err
+	}
+	current, err := buildRevision(*myKind)
+	if err != nil {
+		log.Error(err, "failed to build ControllerRevision for MyKind resource")
+		return err
+	}
+
+	revision := findRollbackRevision(revisions, target, current.Name)
+	if revision == nil {
+		log.Info("revision to roll back to not found")
+		if target == 0 {
+			r.Recorder.Eventf(myKind, core.EventTypeWarning, "RollbackFailed", "Unable to roll back: no previous revision found")
+		} else {
+			r.Recorder.Eventf(myKind, core.EventTypeWarning, "RollbackFailed", "Unable to roll back: revision %d not found", target)
+		}
+		return r.restoreRevision(ctx, log, myKind, nil)
+	}
+
+	return r.restoreRevision(ctx, log, myKind, revision)
+}
+
+// restoreRevision updates the spec of the given MyKind to that recorded in
+// the given revision, leaving the fields that are not recorded in revisions
+// as they are, and removes any rollback request. If revision is nil, only
+// the rollback request is removed.
+func (r *MyKindReconciler) restoreRevision(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, revision *apps.ControllerRevision) error {
+	patched := myKind.DeepCopy()
+	if revision != nil {
+		data := revisionData{}
+		if err := json.Unmarshal(revision.Data.Raw, &data); err != nil {
+			log.Error(err, "failed to decode ControllerRevision", "name", revision.Name)
+			return err
+		}
+		data.Spec.Paused = myKind.Spec.Paused
+		data.Spec.RevisionHistoryLimit = myKind.Spec.RevisionHistoryLimit
+		patched.Spec = data.Spec
+		log.Info("rolling back spec", "revision_name", revision.Name)
+	}
+	patched.Spec.RollbackTo = nil
+	delete(patched.Annotations, mygroupv1.RollbackToAnnotation)
+
+	if err := r.patch(ctx, patched, myKind); err != nil {
+		log.Error(err, "failed to update MyKind resource")
+		return err
+	}
+
+	if revision != nil {
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "RolledBack", "Rolled back to revision %d", revision.Revision)
+	}
+	return nil
+}
+
+// rollbackRequested returns true if a rollback of the given MyKind has been
+// requested by spec.rollbackTo or the RollbackToAnnotation.
+func rollbackRequested(myKind mygroupv1.MyKind) bool {
+	_, ok := myKind.Annotations[mygroupv1.RollbackToAnnotation]
+	return ok || myKind.Spec.RollbackTo != nil
+}
+
+// rollbackRevision returns the number of the revision that the given MyKind
+// is requested to be rolled back to, with spec.rollbackTo taking precedence
+// over the RollbackToAnnotation.
+func rollbackRevision(myKind mygroupv1.MyKind) (int64, error) {
+	if myKind.Spec.RollbackTo != nil {
+		return myKind.Spec.RollbackTo.Revision, nil
+	}
+
+	value := myKind.Annotations[mygroupv1.RollbackToAnnotation]
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || revision < 0 {
+		return 0, fmt.Errorf("invalid revision %q in annotation %s", value, mygroupv1.RollbackToAnnotation)
+	}
+	return revision, nil
+}
+
+// findRollbackRevision returns the revision with the given number, or the
+// latest revision other than the one with the given name if the number is
+// 0. It returns nil if there is no such revision.
+func findRollbackRevision(revisions []apps.ControllerRevision, target int64, current string) *apps.ControllerRevision {
+	sortRevisions(revisions)
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if target == 0 && revisions[i].Name != current {
+			return &revisions[i]
+		}
+		if target != 0 && revisions[i].Revision == target {
+			return &revisions[i]
+		}
+	}
+	return nil
+}
+
+// listOwnedRevisions returns the ControllerRevisions that were created for
+// the given MyKind.
+func (r *MyKindReconciler) listOwnedRevisions(ctx context.Context, myKind *mygroupv1.MyKind) ([]apps.ControllerRevision, error) {
+	var revisions apps.ControllerRevisionList
+	if err := r.List(ctx, &revisions, client.InNamespace(myKind.Namespace), client.MatchingField(revisionOwnerKey, myKind.Name)); err != nil {
+		return nil, err
+	}
+	return revisions.Items, nil
+}
+
+// buildRevision renders a ControllerRevision recording the spec of the
+// given MyKind, other than the fields that are left as they are by a
+// rollback. It is named after the MyKind and a hash of the recorded spec,
+// and its revision number is left unset.
+func buildRevision(myKind mygroupv1.MyKind) (*apps.ControllerRevision, error) {
+	spec := myKind.Spec.DeepCopy()
+	spec.Paused = false
+	spec.RevisionHistoryLimit = nil
+	spec.RollbackTo = nil
+
+	data, err := json.Marshal(revisionData{Spec: *spec})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+
+	revision := apps.ControllerRevision{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apps.SchemeGroupVersion.String(),
+			Kind:       "ControllerRevision",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            myKind.Name + "-" + hex.EncodeToString(sum[:])[:10],
+			Namespace:       myKind.Namespace,
+			Labels:          deploymentLabels(myKind),
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(&myKind, mygroupv1.GroupVersion.WithKind("MyKind"))},
+		},
+		Data: runtime.RawExtension{Raw: data},
+	}
+	return &revision, nil
+}
+
+// sortRevisions sorts the given revisions from oldest to newest.
+func sortRevisions(revisions []apps.ControllerRevision) {
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+func TestBuildRevision(t *testing.T) {
+	myKind := mygroupv1.MyKind{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"},
+		Spec: mygroupv1.MyKindSpec{
+			Deployment: mygroupv1.DeploymentSpec{Name: "web", Replicas: pointer.Int32Ptr(2)},
+		},
+	}
+	base, err := buildRevision(myKind)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	unrecorded := myKind.DeepCopy()
+	unrecorded.Spec.Paused = true
+	unrecorded.Spec.RevisionHistoryLimit = pointer.Int32Ptr(3)
+	unrecorded.Spec.RollbackTo = &mygroupv1.RollbackConfig{Revision: 1}
+	if revision, _ := buildRevision(*unrecorded); revision.Name != base.Name {
+		t.Errorf("expected fields left as they are by a rollback not to change the revision name %q, got %q", base.Name, revision.Name)
+	}
+
+	changed := myKind.DeepCopy()
+	changed.Spec.Deployment.Replicas = pointer.Int32Ptr(3)
+	if revision, _ := buildRevision(*changed); revision.Name == base.Name {
+		t.Errorf("expected a change to the spec to change the revision name %q", base.Name)
+	}
+}
+
+func TestFindRollbackRevision(t *testing.T) {
+	revisions := []apps.ControllerRevision{
+		{ObjectMeta: metav1.ObjectMeta{Name: "test-c"}, Revision: 3},
+		{ObjectMeta: metav1.ObjectMeta{Name: "test-a"}, Revision: 1},
+		{ObjectMeta: metav1.ObjectMeta{Name: "test-b"}, Revision: 2},
+	}
+
+	tests := []struct {
+		name     string
+		target   int64
+		current  string
+		wantName string
+	}{
+		{name: "previous revision", target: 0, current: "test-c", wantName: "test-b"},
+		{name: "previous revision of an unrecorded spec", target: 0, current: "test-d", wantName: "test-c"},
+		{name: "numbered revision", target: 1, current: "test-c", wantName: "test-a"},
+		{name: "missing revision", target: 4, current: "test-c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findRollbackRevision(revisions, tt.target, tt.current)
+			if tt.wantName == "" {
+				if got != nil {
+					t.Errorf("expected no revision, got %q", got.Name)
+				}
+				return
+			}
+			if got == nil || got.Name != tt.wantName {
+				t.Errorf("expected revision %q, got %v", tt.wantName, got)
+			}
+		})
+	}
+}
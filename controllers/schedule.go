@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	core "k8s.io/api/core/v1"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+	"jetstack.io/example-controller/pkg/cron"
+)
+
+// activeSchedule returns the first schedule in spec.schedules of the given
+// MyKind that is active at the given time, or nil if none are, along with
+// the time until the next start or end of any of them, which is 0 if they
+// will never start or end again.
+func activeSchedule(myKind mygroupv1.MyKind, now time.Time) (*mygroupv1.ScaleSchedule, time.Duration, error) {
+	var active *mygroupv1.ScaleSchedule
+	var next time.Time
+
+	for i := range myKind.Spec.Schedules {
+		schedule := &myKind.Spec.Schedules[i]
+		location := time.UTC
+		if schedule.TimeZone != "" {
+			var err error
+			if location, err = time.LoadLocation(schedule.TimeZone); err != nil {
+				return nil, 0, fmt.Errorf("invalid time zone in schedule %q: %v", schedule.Name, err)
+			}
+		}
+		start, err := cron.Parse(schedule.Start)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid start of schedule %q: %v", schedule.Name, err)
+		}
+		end, err := cron.Parse(schedule.End)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid end of schedule %q: %v", schedule.Name, err)
+		}
+
+		local := now.In(location)
+		if active == nil && start.Prev(local).After(end.Prev(local)) {
+			active = schedule
+		}
+		for _, t := range []time.Time{start.Next(local), end.Next(local)} {
+			if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+				next = t
+			}
+		}
+	}
+
+	if next.IsZero() {
+		return active, 0, nil
+	}
+	return active, next.Sub(now), nil
+}
+
+// scheduledMyKind returns a copy of the given MyKind whose workload, and
+// the components listed by the given schedule, are scaled to the replica
+// counts of the schedule, so that they can be rendered and reconciled in the
+// same way as those of the MyKind itself.
+// Events recorded for the copy are recorded for the MyKind.
+func scheduledMyKind(myKind mygroupv1.MyKind, schedule mygroupv1.ScaleSchedule) *mygroupv1.MyKind {
+	scaled := myKind.DeepCopy()
+	replicas := schedule.Replicas
+	scaled.Spec.Deployment.Replicas = &replicas
+	for _, scheduled := range schedule.Components {
+		for i := range scaled.Spec.Components {
+			if scaled.Spec.Components[i].Name == scheduled.Name {
+				componentReplicas := scheduled.Replicas
+				scaled.Spec.Components[i].Deployment.Replicas = &componentReplicas
+			}
+		}
+	}
+	return scaled
+}
+
+// recordScheduleTransition emits an event if the given schedule of the given
+// MyKind has become active, or the previously active schedule has ended,
+// since its status was last updated.
+func (r *MyKindReconciler) recordScheduleTransition(myKind *mygroupv1.MyKind, active *mygroupv1.ScaleSchedule) {
+	previous := myKind.Status.ActiveSchedule
+
+	switch {
+	case active != nil && active.Name != previous:
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "ScheduleStarted", "Scaled to %d replicas by schedule %q", active.Replicas, active.Name)
+	case active == nil && previous != "":
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "ScheduleEnded", "Scaled back to %d replicas as schedule %q has ended", *myKind.Spec.Deployment.Replicas, previous)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/utils/pointer"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+func TestActiveSchedule(t *testing.T) {
+	myKind := mygroupv1.MyKind{
+		Spec: mygroupv1.MyKindSpec{
+			Schedules: []mygroupv1.ScaleSchedule{
+				{Name: "weekend", Start: "0 20 * * fri", End: "0 8 * * mon", Replicas: 0},
+				{Name: "overnight", Start: "0 20 * * *", End: "0 8 * * *", Replicas: 1, TimeZone: "America/New_York"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantName  string
+		wantUntil time.Duration
+	}{
+		{
+			name:      "weekday afternoon",
+			now:       time.Date(2019, time.June, 5, 12, 0, 0, 0, time.UTC),
+			wantUntil: 12 * time.Hour,
+		},
+		{
+			name:      "weekday night in New York",
+			now:       time.Date(2019, time.June, 6, 1, 0, 0, 0, time.UTC),
+			wantName:  "overnight",
+			wantUntil: 11 * time.Hour,
+		},
+		{
+			name:      "weekend",
+			now:       time.Date(2019, time.June, 8, 12, 0, 0, 0, time.UTC),
+			wantName:  "weekend",
+			wantUntil: 12 * time.Hour,
+		},
+		{
+			name:      "end of a schedule",
+			now:       time.Date(2019, time.June, 10, 8, 0, 0, 0, time.UTC),
+			wantName:  "overnight",
+			wantUntil: 4 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active, until, err := activeSchedule(myKind, tt.now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			name := ""
+			if active != nil {
+				name = active.Name
+			}
+			if name != tt.wantName {
+				t.Errorf("expected active schedule %q, got %q", tt.wantName, name)
+			}
+			if until != tt.wantUntil {
+				t.Errorf("expected next schedule boundary in %s, got %s", tt.wantUntil, until)
+			}
+		})
+	}
+}
+
+func TestScheduledMyKind(t *testing.T) {
+	myKind := mygroupv1.MyKind{
+		Spec: mygroupv1.MyKindSpec{
+			Deployment: mygroupv1.DeploymentSpec{Name: "web", Replicas: pointer.Int32Ptr(3)},
+			Components: []mygroupv1.ComponentSpec{
+				{Name: "worker", Deployment: mygroupv1.DeploymentSpec{Name: "worker", Replicas: pointer.Int32Ptr(2)}},
+				{Name: "cache", Deployment: mygroupv1.DeploymentSpec{Name: "cache", Replicas: pointer.Int32Ptr(1)}},
+			},
+		},
+	}
+	schedule := mygroupv1.ScaleSchedule{
+		Name:       "overnight",
+		Replicas:   0,
+		Components: []mygroupv1.ScheduledComponent{{Name: "worker", Replicas: 0}},
+	}
+
+	scaled := scheduledMyKind(myKind, schedule)
+	if got := *scaled.Spec.Deployment.Replicas; got != 0 {
+		t.Errorf("expected the workload to be scaled to 0 replicas, got %d", got)
+	}
+	if got := *scaled.Spec.Components[0].Deployment.Replicas; got != 0 {
+		t.Errorf("expected the listed component to be scaled to 0 replicas, got %d", got)
+	}
+	if got := *scaled.Spec.Components[1].Deployment.Replicas; got != 1 {
+		t.Errorf("expected the component that is not listed to keep 1 replica, got %d", got)
+	}
+	if got := *myKind.Spec.Deployment.Replicas; got != 3 {
+		t.Errorf("expected the MyKind not to be modified, got %d replicas", got)
+	}
+}
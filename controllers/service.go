@@ -0,0 +1,285 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// reconcileService ensures that the Service for the given MyKind exists and
+// is up to date if spec.service is set, along with its preview Service if it
+// is rolled out with a blue/green rollout, and cleans up any Services
+// previously created for it.
+// blueGreen is the state of the blue/green rollout of the MyKind, if any,
+// which determines the colour of the pods that each Service selects.
+func (r *MyKindReconciler) reconcileService(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, blueGreen *mygroupv1.BlueGreenStatus) (ctrl.Result, error) {
+	if err := r.cleanupOwnedServices(ctx, log, myKind); err != nil {
+		log.Error(err, "failed to clean up old Service resources for this MyKind")
+		return ctrl.Result{}, err
+	}
+
+	if myKind.Spec.Service == nil {
+		return ctrl.Result{}, nil
+	}
+
+	var activeColor, previewColor mygroupv1.DeploymentColor
+	if blueGreen != nil {
+		activeColor, previewColor = blueGreen.ActiveColor, blueGreen.PreviewColor
+	}
+
+	result, err := r.reconcileServiceObject(ctx, log, myKind, buildService(*myKind, activeColor))
+	if err != nil || !blueGreenEnabled(*myKind) {
+		return result, err
+	}
+
+	if previewColor == "" {
+		// Without a preview Deployment the preview Service routes traffic
+		// to the active one.
+		previewColor = activeColor
+	}
+	previewResult, err := r.reconcileServiceObject(ctx, log, myKind, buildPreviewService(*myKind, previewColor))
+	result.Requeue = result.Requeue || previewResult.Requeue
+	return result, err
+}
+
+// reconcileServiceObject ensures that the given desired Service exists and
+// is up to date.
+func (r *MyKindReconciler) reconcileServiceObject(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, desired *core.Service) (ctrl.Result, error) {
+	log = log.WithValues("service_name", desired.Name)
+
+	log.Info("checking if an existing Service exists for this resource")
+	service := core.Service{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: desired.Namespace, Name: desired.Name}, &service)
+	if apierrors.IsNotFound(err) {
+		log.Info("could not find existing Service for MyKind, creating one...")
+
+		if err := r.apply(ctx, desired); err != nil {
+			log.Error(err, "failed to create Service resource")
+			return ctrl.Result{}, err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Created", "Created service %q", desired.Name)
+		log.Info("created Service resource for MyKind")
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		log.Error(err, "failed to get Service for MyKind resource")
+		return ctrl.Result{}, err
+	}
+
+	if !metav1.IsControlledBy(&service, myKind) {
+		return ctrl.Result{}, fmt.Errorf("service %q already exists and is not controlled by this MyKind", service.Name)
+	}
+
+	log.Info("checking the Service for drift")
+	if (service.Spec.ClusterIP == core.ClusterIPNone) != (desired.Spec.ClusterIP == core.ClusterIPNone) {
+		// The cluster IP of a Service is immutable, so the only way to
+		// switch between a headless and a normal Service is to delete the
+		// Service and create it again.
+		log.Info("Service headless mode has changed, deleting Service so that it is recreated")
+
+		if err := r.Client.Delete(ctx, &service); err != nil {
+			log.Error(err, "failed to delete Service")
+			return ctrl.Result{}, err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Deleted", "Deleted service %q to change spec.clusterIP", service.Name)
+
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	desired.Spec.Ports = preserveNodePorts(&service, desired)
+	if corrected := correctServiceDrift(service.DeepCopy(), desired); len(corrected) > 0 {
+		log.Info("applying Service", "fields", corrected)
+
+		if err := r.apply(ctx, desired); err != nil {
+			log.Error(err, "failed to apply Service")
+			return ctrl.Result{}, err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "DriftCorrected", "Corrected drift in service %q: %s", service.Name, strings.Join(corrected, ", "))
+
+		return ctrl.Result{}, nil
+	}
+
+	log.Info("Service up to date")
+
+	return ctrl.Result{}, nil
+}
+
+// cleanupOwnedServices will Delete any existing Service resources that were
+// created for the given MyKind that no longer match the
+// myKind.spec.deployment.name field, or all of them if myKind.spec.service
+// is not set. The preview Service is only kept while the MyKind is rolled
+// out with a blue/green rollout.
+func (r *MyKindReconciler) cleanupOwnedServices(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) error {
+	log.Info("finding existing Services for MyKind resource")
+
+	// List all service resources owned by this MyKind
+	var services core.ServiceList
+	if err := r.List(ctx, &services, client.InNamespace(myKind.Namespace), client.MatchingField(serviceOwnerKey, myKind.Name)); err != nil {
+		return err
+	}
+
+	deleted := 0
+	for _, svc := range services.Items {
+		if myKind.Spec.Service != nil && svc.Name == myKind.Spec.Deployment.Name {
+			// If this service's name matches the one on the MyKind resource
+			// then do not delete it.
+			continue
+		}
+		if myKind.Spec.Service != nil && blueGreenEnabled(*myKind) && svc.Name == previewServiceName(*myKind) {
+			continue
+		}
+
+		if err := r.Client.Delete(ctx, &svc); err != nil {
+			log.Error(err, "failed to delete Service resource")
+			return err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Deleted", "Deleted service %q", svc.Name)
+		deleted++
+	}
+
+	log.Info("finished cleaning up old Service resources", "number_deleted", deleted)
+
+	return nil
+}
+
+// buildService renders the Service for the given MyKind, which must have
+// spec.service set. If a colour is given, the Service only selects the pods
+// of the Deployment of that colour.
+func buildService(myKind mygroupv1.MyKind, color mygroupv1.DeploymentColor) *core.Service {
+	spec := myKind.Spec.Service.DeepCopy()
+
+	service := core.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: core.SchemeGroupVersion.String(),
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            myKind.Spec.Deployment.Name,
+			Namespace:       myKind.Namespace,
+			Labels:          deploymentLabels(myKind),
+			Annotations:     spec.Annotations,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(&myKind, mygroupv1.GroupVersion.WithKind("MyKind"))},
+		},
+		Spec: core.ServiceSpec{
+			Type:     spec.Type,
+			Ports:    spec.Ports,
+			Selector: colorSelector(myKind, color),
+		},
+	}
+	if spec.Headless {
+		service.Spec.ClusterIP = core.ClusterIPNone
+	}
+	return &service
+}
+
+// buildPreviewService renders the preview Service for the given MyKind,
+// which selects the pods of the Deployment of the given colour.
+// Node ports specified for the Service are allocated by the apiserver for
+// the preview Service instead, as they may only be used by one Service.
+func buildPreviewService(myKind mygroupv1.MyKind, color mygroupv1.DeploymentColor) *core.Service {
+	service := buildService(myKind, color)
+	service.Name = previewServiceName(myKind)
+	for i := range service.Spec.Ports {
+		service.Spec.Ports[i].NodePort = 0
+	}
+	return service
+}
+
+// previewServiceName returns the name of the preview Service of the given
+// MyKind.
+func previewServiceName(myKind mygroupv1.MyKind) string {
+	return myKind.Spec.Deployment.Name + mygroupv1.PreviewServiceSuffix
+}
+
+// correctServiceDrift compares the fields of an existing Service that are
+// owned by the controller against the desired Service built for a MyKind,
+// and copies the desired values onto existing wherever they differ.
+// Node ports allocated by the apiserver are preserved where the desired
+// port does not specify one.
+// It returns the path of each field that was corrected.
+func correctServiceDrift(existing, desired *core.Service) []string {
+	var corrected []string
+
+	if !labelsUpToDate(existing.Labels, desired.Labels) {
+		existing.Labels = mergeLabels(existing.Labels, desired.Labels)
+		corrected = append(corrected, "metadata.labels")
+	}
+	if !labelsUpToDate(existing.Annotations, desired.Annotations) {
+		existing.Annotations = mergeLabels(existing.Annotations, desired.Annotations)
+		corrected = append(corrected, "metadata.annotations")
+	}
+	if existing.Spec.Type != desired.Spec.Type {
+		existing.Spec.Type = desired.Spec.Type
+		corrected = append(corrected, "spec.type")
+	}
+	if !apiequality.Semantic.DeepEqual(existing.Spec.Selector, desired.Spec.Selector) {
+		existing.Spec.Selector = desired.Spec.Selector
+		corrected = append(corrected, "spec.selector")
+	}
+
+	ports := preserveNodePorts(existing, desired)
+	if !apiequality.Semantic.DeepEqual(existing.Spec.Ports, ports) {
+		existing.Spec.Ports = ports
+		corrected = append(corrected, "spec.ports")
+	}
+
+	return corrected
+}
+
+// preserveNodePorts returns a copy of the ports of the desired Service in
+// which any port that does not specify a node port takes the node port of
+// the matching port of the existing Service, if the desired Service type is
+// allocated node ports. Node ports are dropped when the Service is changed
+// to a type that is not allocated them, such as ClusterIP.
+func preserveNodePorts(existing, desired *core.Service) []core.ServicePort {
+	ports := make([]core.ServicePort, len(desired.Spec.Ports))
+	copy(ports, desired.Spec.Ports)
+
+	if desired.Spec.Type != core.ServiceTypeNodePort && desired.Spec.Type != core.ServiceTypeLoadBalancer {
+		return ports
+	}
+
+	for i := range ports {
+		if ports[i].NodePort != 0 {
+			continue
+		}
+		for _, p := range existing.Spec.Ports {
+			if p.Name == ports[i].Name && p.Port == ports[i].Port && p.Protocol == ports[i].Protocol {
+				ports[i].NodePort = p.NodePort
+				break
+			}
+		}
+	}
+
+	return ports
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+func TestPreserveNodePorts(t *testing.T) {
+	existing := &core.Service{
+		Spec: core.ServiceSpec{
+			Type: core.ServiceTypeNodePort,
+			Ports: []core.ServicePort{
+				{Name: "http", Port: 80, Protocol: core.ProtocolTCP, NodePort: 30080},
+			},
+		},
+	}
+	desiredPorts := []core.ServicePort{
+		{Name: "http", Port: 80, Protocol: core.ProtocolTCP},
+	}
+
+	tests := []struct {
+		name         string
+		desiredType  core.ServiceType
+		wantNodePort int32
+	}{
+		{name: "node port", desiredType: core.ServiceTypeNodePort, wantNodePort: 30080},
+		{name: "load balancer", desiredType: core.ServiceTypeLoadBalancer, wantNodePort: 30080},
+		{name: "changed to cluster IP", desiredType: core.ServiceTypeClusterIP},
+		{name: "changed to default type", desiredType: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desired := &core.Service{
+				Spec: core.ServiceSpec{Type: tt.desiredType, Ports: desiredPorts},
+			}
+			ports := preserveNodePorts(existing, desired)
+			if len(ports) != 1 {
+				t.Fatalf("expected 1 port, got %d", len(ports))
+			}
+			if ports[0].NodePort != tt.wantNodePort {
+				t.Errorf("expected node port %d, got %d", tt.wantNodePort, ports[0].NodePort)
+			}
+			if desiredPorts[0].NodePort != 0 {
+				t.Errorf("desired ports were modified")
+			}
+		})
+	}
+}
+
+func TestBuildServiceSelector(t *testing.T) {
+	myKind := mygroupv1.MyKind{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"},
+		Spec: mygroupv1.MyKindSpec{
+			Deployment: mygroupv1.DeploymentSpec{Name: "web"},
+			Service: &mygroupv1.ServiceSpec{
+				Type:  core.ServiceTypeNodePort,
+				Ports: []core.ServicePort{{Name: "http", Port: 80, NodePort: 30080}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		service      *core.Service
+		wantName     string
+		wantSelector map[string]string
+		wantNodePort int32
+	}{
+		{
+			name:         "without a colour",
+			service:      buildService(myKind, ""),
+			wantName:     "web",
+			wantSelector: map[string]string{mygroupv1.DeploymentNameLabel: "web"},
+			wantNodePort: 30080,
+		},
+		{
+			name:         "active colour",
+			service:      buildService(myKind, mygroupv1.DeploymentColorGreen),
+			wantName:     "web",
+			wantSelector: map[string]string{mygroupv1.DeploymentNameLabel: "web", mygroupv1.ColorLabel: "green"},
+			wantNodePort: 30080,
+		},
+		{
+			name:         "preview colour",
+			service:      buildPreviewService(myKind, mygroupv1.DeploymentColorBlue),
+			wantName:     "web-preview",
+			wantSelector: map[string]string{mygroupv1.DeploymentNameLabel: "web", mygroupv1.ColorLabel: "blue"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.service.Name != tt.wantName {
+				t.Errorf("expected name %q, got %q", tt.wantName, tt.service.Name)
+			}
+			if !apiequality.Semantic.DeepEqual(tt.service.Spec.Selector, tt.wantSelector) {
+				t.Errorf("expected selector %v, got %v", tt.wantSelector, tt.service.Spec.Selector)
+			}
+			if np := tt.service.Spec.Ports[0].NodePort; np != tt.wantNodePort {
+				t.Errorf("expected node port %d, got %d", tt.wantNodePort, np)
+			}
+		})
+	}
+	if np := myKind.Spec.Service.Ports[0].NodePort; np != 30080 {
+		t.Errorf("spec.service was modified")
+	}
+}
@@ -0,0 +1,622 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// observedState holds the resources managed for a MyKind as last observed
+// or written by the controller. Each may be nil if it does not exist or
+// could not be retrieved or created.
+// The workload is always of the kind given by spec.workloadKind, and the
+// workloads of the components are in the order of spec.components.
+// canary is the state of the canary rollout of the workload, if one is in
+// progress, and canaryDeployment is its canary Deployment.
+// blueGreen is the state of the blue/green rollout of the workload, if it
+// is rolled out with one, in which case the workload is the active
+// Deployment and previewDeployment is the Deployment of the other colour.
+// revision is the name of the ControllerRevision of the reconciled spec, and
+// is empty if the spec was not reconciled. schedule is the schedule that the
+// workload was scaled by when the spec was reconciled, if any.
+type observedState struct {
+	revision          string
+	schedule          *mygroupv1.ScaleSchedule
+	workload          workloadObject
+	components        []workloadObject
+	canary            *mygroupv1.CanaryStatus
+	canaryDeployment  *apps.Deployment
+	blueGreen         *mygroupv1.BlueGreenStatus
+	previewDeployment *apps.Deployment
+	ingress           *networking.Ingress
+}
+
+// summarizeWorkload returns a summary of the given workload, or nil if it is
+// nil.
+func summarizeWorkload(workload workloadObject) *workloadSummary {
+	switch w := workload.(type) {
+	case *apps.Deployment:
+		return summarizeDeployment(w)
+	case *apps.StatefulSet:
+		return summarizeStatefulSet(w)
+	case *apps.DaemonSet:
+		return summarizeDaemonSet(w)
+	}
+	return nil
+}
+
+// workloadSummary describes the state of a workload of any kind in the terms
+// used by the status of a MyKind.
+type workloadSummary struct {
+	name string
+
+	// desiredReplicas is the number of pods the workload should be running.
+	desiredReplicas     int32
+	replicas            int32
+	readyReplicas       int32
+	updatedReplicas     int32
+	availableReplicas   int32
+	unavailableReplicas int32
+
+	// complete is true if the latest spec of the workload has been
+	// observed and all of its pods are updated and available.
+	complete bool
+
+	// available is the status of the Available condition of the MyKind
+	// according to the workload, along with its reason and message.
+	available        core.ConditionStatus
+	availableReason  string
+	availableMessage string
+
+	// failureReason and failureMessage are set if the workload has
+	// reported that it is unable to make progress.
+	failureReason  string
+	failureMessage string
+}
+
+// summarizeDeployment returns a summary of the given Deployment.
+func summarizeDeployment(deployment *apps.Deployment) *workloadSummary {
+	s := &workloadSummary{
+		name:                deployment.Name,
+		desiredReplicas:     deploymentReplicas(deployment),
+		replicas:            deployment.Status.Replicas,
+		readyReplicas:       deployment.Status.ReadyReplicas,
+		updatedReplicas:     deployment.Status.UpdatedReplicas,
+		availableReplicas:   deployment.Status.AvailableReplicas,
+		unavailableReplicas: deployment.Status.UnavailableReplicas,
+		complete:            deploymentComplete(deployment),
+	}
+
+	if c := getDeploymentCondition(deployment.Status, apps.DeploymentAvailable); c != nil {
+		s.available, s.availableReason, s.availableMessage = c.Status, c.Reason, c.Message
+	} else {
+		s.available = core.ConditionUnknown
+		s.availableReason = "DeploymentConditionUnknown"
+		s.availableMessage = fmt.Sprintf("Deployment %q has not reported availability", deployment.Name)
+	}
+
+	if c := getDeploymentCondition(deployment.Status, apps.DeploymentProgressing); c != nil && c.Reason == "ProgressDeadlineExceeded" {
+		s.failureReason, s.failureMessage = c.Reason, c.Message
+	}
+
+	return s
+}
+
+// summarizeStatefulSet returns a summary of the given StatefulSet.
+// StatefulSets do not report available replicas, so ready replicas are
+// counted as available.
+func summarizeStatefulSet(statefulSet *apps.StatefulSet) *workloadSummary {
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+
+	s := &workloadSummary{
+		name:              statefulSet.Name,
+		desiredReplicas:   replicas,
+		replicas:          statefulSet.Status.Replicas,
+		readyReplicas:     statefulSet.Status.ReadyReplicas,
+		updatedReplicas:   statefulSet.Status.UpdatedReplicas,
+		availableReplicas: statefulSet.Status.ReadyReplicas,
+	}
+	if statefulSet.Status.ReadyReplicas < replicas {
+		s.unavailableReplicas = replicas - statefulSet.Status.ReadyReplicas
+	}
+	s.complete = statefulSet.Status.ObservedGeneration >= statefulSet.Generation &&
+		statefulSet.Status.UpdatedReplicas == replicas &&
+		statefulSet.Status.Replicas == replicas &&
+		statefulSet.Status.ReadyReplicas == replicas
+
+	s.available, s.availableReason = core.ConditionTrue, "MinimumReplicasAvailable"
+	s.availableMessage = fmt.Sprintf("StatefulSet %q has %d of %d replicas ready", statefulSet.Name, statefulSet.Status.ReadyReplicas, replicas)
+	if s.unavailableReplicas > 0 {
+		s.available, s.availableReason = core.ConditionFalse, "MinimumReplicasUnavailable"
+	}
+
+	return s
+}
+
+// summarizeDaemonSet returns a summary of the given DaemonSet, in which
+// replicas are the pods scheduled by it.
+func summarizeDaemonSet(daemonSet *apps.DaemonSet) *workloadSummary {
+	desired := daemonSet.Status.DesiredNumberScheduled
+
+	s := &workloadSummary{
+		name:                daemonSet.Name,
+		desiredReplicas:     desired,
+		replicas:            daemonSet.Status.CurrentNumberScheduled,
+		readyReplicas:       daemonSet.Status.NumberReady,
+		updatedReplicas:     daemonSet.Status.UpdatedNumberScheduled,
+		availableReplicas:   daemonSet.Status.NumberAvailable,
+		unavailableReplicas: daemonSet.Status.NumberUnavailable,
+	}
+	s.complete = daemonSet.Status.ObservedGeneration >= daemonSet.Generation &&
+		daemonSet.Status.UpdatedNumberScheduled == desired &&
+		daemonSet.Status.CurrentNumberScheduled == desired &&
+		daemonSet.Status.NumberAvailable == desired
+
+	s.available, s.availableReason = core.ConditionTrue, "MinimumReplicasAvailable"
+	s.availableMessage = fmt.Sprintf("DaemonSet %q has %d of %d scheduled pods available", daemonSet.Name, daemonSet.Status.NumberAvailable, desired)
+	if daemonSet.Status.NumberAvailable < desired {
+		s.available, s.availableReason = core.ConditionFalse, "MinimumReplicasUnavailable"
+	}
+
+	return s
+}
+
+// syncStatus computes the status of the given MyKind and writes it with a
+// merge patch if it differs from the status last written.
+// The patch includes the resourceVersion of the MyKind so that it is
+// rejected if the MyKind has been modified since it was read. On conflict
+// the latest version is fetched and the status is recomputed against it,
+// so that condition transition times are not lost.
+// On success, myKind is updated with the written status.
+func (r *MyKindReconciler) syncStatus(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, observed observedState, reconcileErr error) error {
+	latest := myKind.DeepCopy()
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// The status always describes the spec and generation that were
+		// reconciled, even if the MyKind has since been modified.
+		reconciled := *myKind.DeepCopy()
+		reconciled.Status = latest.Status
+		status := computeStatus(reconciled, observed, reconcileErr, metav1.NewTime(r.now()))
+
+		if apiequality.Semantic.DeepEqual(latest.Status, status) {
+			log.Info("resource status up to date")
+			myKind.Status = status
+			return nil
+		}
+
+		log.Info("updating MyKind resource status")
+		base := latest.DeepCopy()
+		base.ResourceVersion = ""
+		patched := latest.DeepCopy()
+		patched.Status = status
+		err := r.Client.Status().Patch(ctx, patched, client.MergeFrom(base))
+		if apierrors.IsConflict(err) {
+			log.Info("MyKind resource was modified while updating status, retrying")
+			if getErr := r.Client.Get(ctx, client.ObjectKey{Namespace: myKind.Namespace, Name: myKind.Name}, latest); getErr != nil {
+				return getErr
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		log.Info("resource status synced")
+		myKind.ResourceVersion = patched.ResourceVersion
+		myKind.Status = status
+		return nil
+	})
+}
+
+// syncStatusAfterError updates the status of the given MyKind to report an
+// error that stopped its resources from being reconciled, based on their
+// current state. It returns reconcileErr.
+func (r *MyKindReconciler) syncStatusAfterError(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, reconcileErr error) error {
+	observed, err := r.observeResources(ctx, log, myKind)
+	if err != nil {
+		return reconcileErr
+	}
+	if err := r.syncStatus(ctx, log, myKind, observed, reconcileErr); err != nil {
+		log.Error(err, "failed to update MyKind status")
+	}
+	return reconcileErr
+}
+
+// observeResources retrieves the resources managed for the given MyKind
+// without creating, updating or deleting anything, so that its status can
+// be kept up to date when they are not being reconciled.
+func (r *MyKindReconciler) observeResources(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) (observedState, error) {
+	var observed observedState
+	var err error
+
+	if blueGreenEnabled(*myKind) {
+		observed.blueGreen, observed.workload, observed.previewDeployment, err = r.observeBlueGreen(ctx, log, myKind)
+		if err != nil {
+			return observed, err
+		}
+	} else {
+		workload := newWorkload(workloadKind(*myKind))
+		found, err := r.getControlled(ctx, myKind, workload)
+		if err != nil {
+			log.Error(err, "failed to get workload for MyKind resource", "kind", workloadKind(*myKind))
+			return observed, err
+		}
+		if found {
+			observed.workload = workload
+		}
+	}
+
+	observed.components, err = r.observeComponents(ctx, log, myKind)
+	if err != nil {
+		return observed, err
+	}
+
+	observed.canary, observed.canaryDeployment, err = r.observeCanary(ctx, log, myKind)
+	if err != nil {
+		return observed, err
+	}
+
+	if myKind.Spec.Ingress != nil {
+		ingress := &networking.Ingress{}
+		found, err := r.getControlled(ctx, myKind, ingress)
+		if err != nil {
+			log.Error(err, "failed to get Ingress for MyKind resource")
+			return observed, err
+		}
+		if found {
+			observed.ingress = ingress
+		}
+	}
+
+	return observed, nil
+}
+
+// getControlled retrieves the resource of obj's type named after the
+// workload of the given MyKind into obj, and returns true if it exists and
+// is controlled by the MyKind.
+func (r *MyKindReconciler) getControlled(ctx context.Context, myKind *mygroupv1.MyKind, obj runtime.Object) (bool, error) {
+	return r.getControlledByName(ctx, myKind, myKind.Spec.Deployment.Name, obj)
+}
+
+// getControlledByName retrieves the resource of obj's type with the given
+// name in the namespace of the given MyKind into obj, and returns true if it
+// exists and is controlled by the MyKind.
+func (r *MyKindReconciler) getControlledByName(ctx context.Context, myKind *mygroupv1.MyKind, name string, obj runtime.Object) (bool, error) {
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: myKind.Namespace, Name: name}, obj)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
+	return metav1.IsControlledBy(accessor, myKind), nil
+}
+
+// computeStatus returns the status of the given MyKind based on the last
+// observed state of the resources managed for it and the outcome of the
+// reconcile. Conditions that change status are given now as their last
+// transition time.
+func computeStatus(myKind mygroupv1.MyKind, observed observedState, reconcileErr error, now metav1.Time) mygroupv1.MyKindStatus {
+	kind := workloadKind(myKind)
+	workload := summarizeWorkload(observed.workload)
+
+	status := *myKind.Status.DeepCopy()
+	status.ObservedGeneration = myKind.Generation
+	status.DeploymentName = ""
+	status.WorkloadKind = ""
+	status.Selector = labels.SelectorFromSet(deploymentLabels(myKind)).String()
+	status.Replicas = 0
+	status.ReadyReplicas = 0
+	status.UpdatedReplicas = 0
+	status.AvailableReplicas = 0
+	status.UnavailableReplicas = 0
+	status.Components = computeComponentStatuses(myKind, observed.components)
+	status.Canary = computeCanaryStatus(observed.canary, observed.canaryDeployment)
+	status.BlueGreen = observed.blueGreen.DeepCopy()
+	if observed.revision != "" {
+		status.CurrentRevision = observed.revision
+		status.ActiveSchedule = ""
+		if observed.schedule != nil {
+			status.ActiveSchedule = observed.schedule.Name
+		}
+	}
+	status.LoadBalancer = core.LoadBalancerStatus{}
+	if observed.ingress != nil {
+		status.LoadBalancer = *observed.ingress.Status.LoadBalancer.DeepCopy()
+	}
+
+	available := mygroupv1.MyKindCondition{
+		Type:    mygroupv1.MyKindDeploymentAvailable,
+		Status:  core.ConditionUnknown,
+		Reason:  string(kind) + "NotFound",
+		Message: fmt.Sprintf("%s %q has not been observed", kind, myKind.Spec.Deployment.Name),
+	}
+	progressing := mygroupv1.MyKindCondition{
+		Type:    mygroupv1.MyKindProgressing,
+		Status:  core.ConditionFalse,
+		Reason:  string(kind) + "NotFound",
+		Message: fmt.Sprintf("%s %q has not been observed", kind, myKind.Spec.Deployment.Name),
+	}
+	degraded := mygroupv1.MyKindCondition{
+		Type:   mygroupv1.MyKindDegraded,
+		Status: core.ConditionFalse,
+		Reason: "AsExpected",
+	}
+
+	if workload != nil {
+		status.DeploymentName = workload.name
+		status.WorkloadKind = kind
+		status.Replicas = workload.replicas
+		status.ReadyReplicas = workload.readyReplicas
+		status.UpdatedReplicas = workload.updatedReplicas
+		status.AvailableReplicas = workload.availableReplicas
+		status.UnavailableReplicas = workload.unavailableReplicas
+
+		available.Status = workload.available
+		available.Reason = workload.availableReason
+		available.Message = workload.availableMessage
+
+		if workload.complete {
+			progressing.Status = core.ConditionFalse
+			progressing.Reason = string(kind) + "Complete"
+			progressing.Message = fmt.Sprintf("%s %q has been rolled out", kind, workload.name)
+		} else {
+			progressing.Status = core.ConditionTrue
+			progressing.Reason = string(kind) + "RollingOut"
+			progressing.Message = fmt.Sprintf("Waiting for %s %q to roll out: %d of %d replicas updated",
+				workloadKindName(kind), workload.name, workload.updatedReplicas, workload.desiredReplicas)
+		}
+
+		if reason, message := checkHealth(myKind, workload); reason != "" {
+			degraded.Status = core.ConditionTrue
+			degraded.Reason = reason
+			degraded.Message = message
+		}
+	}
+
+	if canary := status.Canary; canary != nil {
+		steps := 0
+		if canaryEnabled(myKind) {
+			steps = len(myKind.Spec.Rollout.Canary.Steps)
+		}
+		switch canary.Phase {
+		case mygroupv1.CanaryPhaseAborted:
+			degraded.Status = core.ConditionTrue
+			degraded.Reason = "CanaryAborted"
+			degraded.Message = fmt.Sprintf("Canary rollout of deployment %q was aborted, the previous pod template is running", myKind.Spec.Deployment.Name)
+		case mygroupv1.CanaryPhasePromoting:
+			progressing.Status = core.ConditionTrue
+			progressing.Reason = "CanaryPromoting"
+			progressing.Message = fmt.Sprintf("Waiting for deployment %q to roll out the promoted canary pod template", myKind.Spec.Deployment.Name)
+		default:
+			progressing.Status = core.ConditionTrue
+			progressing.Reason = "Canary" + string(canary.Phase)
+			progressing.Message = fmt.Sprintf("Canary rollout of deployment %q is at step %d of %d", myKind.Spec.Deployment.Name, canary.CurrentStep+1, steps)
+		}
+	}
+
+	if blueGreen := status.BlueGreen; blueGreen != nil && blueGreen.ActiveColor != "" && blueGreen.PreviewColor != "" {
+		preview := colorDeploymentName(myKind, blueGreen.PreviewColor)
+		progressing.Status = core.ConditionTrue
+		if blueGreen.PreviewAvailable {
+			progressing.Reason = "BlueGreenAwaitingPromotion"
+			progressing.Message = fmt.Sprintf("Preview deployment %q is available and awaiting promotion", preview)
+		} else {
+			progressing.Reason = "BlueGreenPreviewRollingOut"
+			progressing.Message = fmt.Sprintf("Waiting for preview deployment %q to roll out", preview)
+		}
+	}
+
+	conflict := mygroupv1.MyKindCondition{
+		Type:   mygroupv1.MyKindDeploymentNameConflict,
+		Status: core.ConditionFalse,
+		Reason: "NoConflict",
+	}
+
+	if reconcileErr != nil {
+		degraded.Status = core.ConditionTrue
+		degraded.Reason = "ReconcileError"
+		degraded.Message = reconcileErr.Error()
+
+		if err, ok := reconcileErr.(*deploymentNameConflictError); ok {
+			conflict.Status = core.ConditionTrue
+			conflict.Reason = err.reason
+			conflict.Message = err.Error()
+			degraded.Reason = "DeploymentNameConflict"
+		}
+	}
+
+	ready := mygroupv1.MyKindCondition{
+		Type:   mygroupv1.MyKindReady,
+		Status: core.ConditionTrue,
+		Reason: string(kind) + "Ready",
+	}
+	switch {
+	case degraded.Status == core.ConditionTrue:
+		ready.Status, ready.Reason, ready.Message = core.ConditionFalse, degraded.Reason, degraded.Message
+	case available.Status != core.ConditionTrue:
+		ready.Status, ready.Reason, ready.Message = core.ConditionFalse, string(kind)+"Unavailable", available.Message
+	case unavailableComponent(status.Components) != "":
+		ready.Status, ready.Reason = core.ConditionFalse, "ComponentUnavailable"
+		ready.Message = fmt.Sprintf("Deployment of component %q is not available", unavailableComponent(status.Components))
+	case progressing.Status == core.ConditionTrue:
+		ready.Status, ready.Reason, ready.Message = core.ConditionFalse, progressing.Reason, progressing.Message
+	}
+
+	paused := mygroupv1.MyKindCondition{
+		Type:   mygroupv1.MyKindPaused,
+		Status: core.ConditionFalse,
+		Reason: "Reconciling",
+	}
+	if reason := pauseReason(myKind); reason != "" {
+		paused.Status = core.ConditionTrue
+		paused.Reason = reason
+		paused.Message = "The controller is not modifying any resources managed for this MyKind"
+	}
+
+	// A revision is only known to be good once it has been rolled out and
+	// the MyKind has become Ready.
+	if ready.Status == core.ConditionTrue && observed.revision != "" {
+		status.LastKnownGoodRevision = observed.revision
+	}
+
+	for _, c := range []mygroupv1.MyKindCondition{ready, progressing, degraded, available, conflict, paused} {
+		c.ObservedGeneration = myKind.Generation
+		status.Conditions = setCondition(status.Conditions, c, now)
+	}
+
+	return status
+}
+
+// computeComponentStatuses returns the status of each component of the
+// given MyKind based on the last observed state of its Deployment. workloads
+// are in the order of spec.components.
+func computeComponentStatuses(myKind mygroupv1.MyKind, workloads []workloadObject) []mygroupv1.ComponentStatus {
+	var statuses []mygroupv1.ComponentStatus
+	for i, component := range myKind.Spec.Components {
+		status := mygroupv1.ComponentStatus{
+			Name:      component.Name,
+			Available: core.ConditionUnknown,
+		}
+		if i < len(workloads) {
+			if workload := summarizeWorkload(workloads[i]); workload != nil {
+				status.DeploymentName = workload.name
+				status.Replicas = workload.replicas
+				status.ReadyReplicas = workload.readyReplicas
+				status.UpdatedReplicas = workload.updatedReplicas
+				status.AvailableReplicas = workload.availableReplicas
+				status.Available = workload.available
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// computeCanaryStatus returns the status of the canary rollout of a MyKind
+// with the given state, or nil if none is in progress, based on the last
+// observed state of its canary Deployment.
+func computeCanaryStatus(state *mygroupv1.CanaryStatus, deployment *apps.Deployment) *mygroupv1.CanaryStatus {
+	if state == nil {
+		return nil
+	}
+
+	status := state.DeepCopy()
+	status.Replicas = 0
+	status.ReadyReplicas = 0
+	if deployment != nil {
+		status.Replicas = deployment.Status.Replicas
+		status.ReadyReplicas = deployment.Status.ReadyReplicas
+	}
+	return status
+}
+
+// unavailableComponent returns the name of the first component whose
+// Deployment is not available, or an empty string if they all are.
+func unavailableComponent(statuses []mygroupv1.ComponentStatus) string {
+	for _, status := range statuses {
+		if status.Available != core.ConditionTrue {
+			return status.Name
+		}
+	}
+	return ""
+}
+
+// setCondition adds or replaces the condition of the same type as c in
+// conditions. The last transition time is only updated, to now, if the
+// status of the condition has changed.
+func setCondition(conditions []mygroupv1.MyKindCondition, c mygroupv1.MyKindCondition, now metav1.Time) []mygroupv1.MyKindCondition {
+	for i := range conditions {
+		if conditions[i].Type != c.Type {
+			continue
+		}
+		if conditions[i].Status == c.Status {
+			c.LastTransitionTime = conditions[i].LastTransitionTime
+		} else {
+			c.LastTransitionTime = now
+		}
+		conditions[i] = c
+		return conditions
+	}
+
+	c.LastTransitionTime = now
+	return append(conditions, c)
+}
+
+// conditionStatus returns the status of the condition of the given type in
+// conditions, or Unknown if it is not set.
+func conditionStatus(conditions []mygroupv1.MyKindCondition, condType mygroupv1.MyKindConditionType) core.ConditionStatus {
+	for _, c := range conditions {
+		if c.Type == condType {
+			return c.Status
+		}
+	}
+	return core.ConditionUnknown
+}
+
+// getDeploymentCondition returns the condition of the given type from the
+// status of a Deployment, or nil if it is not set.
+func getDeploymentCondition(status apps.DeploymentStatus, condType apps.DeploymentConditionType) *apps.DeploymentCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == condType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// deploymentReplicas returns the desired number of replicas of a Deployment.
+func deploymentReplicas(deployment *apps.Deployment) int32 {
+	if deployment.Spec.Replicas == nil {
+		return 1
+	}
+	return *deployment.Spec.Replicas
+}
+
+// deploymentComplete returns true if the latest spec of the Deployment has
+// been observed and all of its replicas are updated and available.
+func deploymentComplete(deployment *apps.Deployment) bool {
+	replicas := deploymentReplicas(deployment)
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.UpdatedReplicas == replicas &&
+		deployment.Status.Replicas == replicas &&
+		deployment.Status.AvailableReplicas == replicas
+}
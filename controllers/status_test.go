@@ -0,0 +1,213 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// conflictingClient wraps a client.Client and fails the first conflicts
+// status patches with a conflict error.
+type conflictingClient struct {
+	client.Client
+	conflicts     int
+	statusPatches int
+}
+
+func (c *conflictingClient) Status() client.StatusWriter {
+	return &conflictingStatusWriter{StatusWriter: c.Client.Status(), parent: c}
+}
+
+type conflictingStatusWriter struct {
+	client.StatusWriter
+	parent *conflictingClient
+}
+
+func (w *conflictingStatusWriter) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOptionFunc) error {
+	w.parent.statusPatches++
+	if w.parent.conflicts > 0 {
+		w.parent.conflicts--
+		return apierrors.NewConflict(mygroupv1.GroupVersion.WithResource("mykinds").GroupResource(), "testresource", errors.New("the object has been modified"))
+	}
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+func newTestStatusMyKind() *mygroupv1.MyKind {
+	myKind := &mygroupv1.MyKind{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "testresource",
+			Namespace:  "testns",
+			Generation: 2,
+		},
+		Spec: mygroupv1.MyKindSpec{
+			Deployment: mygroupv1.DeploymentSpec{
+				Name: "deployment-name",
+			},
+		},
+	}
+	myKind.Default()
+	return myKind
+}
+
+func newTestStatusReconciler(t *testing.T, conflicts int, objs ...runtime.Object) (*MyKindReconciler, *conflictingClient) {
+	scheme := runtime.NewScheme()
+	if err := mygroupv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+
+	c := &conflictingClient{Client: fake.NewFakeClientWithScheme(scheme, objs...), conflicts: conflicts}
+	return &MyKindReconciler{Client: c, Log: logf.Log}, c
+}
+
+func TestSyncStatus(t *testing.T) {
+	deployment := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "deployment-name"},
+		Status: apps.DeploymentStatus{
+			Replicas:          1,
+			AvailableReplicas: 1,
+		},
+	}
+
+	tests := map[string]struct {
+		conflicts         int
+		wantErr           bool
+		wantStatusPatches int
+	}{
+		"no conflicts": {
+			wantStatusPatches: 1,
+		},
+		"a single conflict": {
+			conflicts:         1,
+			wantStatusPatches: 2,
+		},
+		"persistent conflicts": {
+			conflicts:         retry.DefaultRetry.Steps,
+			wantErr:           true,
+			wantStatusPatches: retry.DefaultRetry.Steps,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			myKind := newTestStatusMyKind()
+			r, c := newTestStatusReconciler(t, test.conflicts, myKind.DeepCopy())
+
+			err := r.syncStatus(ctx, r.Log, myKind, observedState{workload: deployment}, nil)
+			if test.wantErr {
+				if !apierrors.IsConflict(err) {
+					t.Fatalf("expected conflict error, got: %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.statusPatches != test.wantStatusPatches {
+				t.Errorf("expected %d status patches, got %d", test.wantStatusPatches, c.statusPatches)
+			}
+			if test.wantErr {
+				return
+			}
+
+			persisted := &mygroupv1.MyKind{}
+			if err := c.Get(ctx, client.ObjectKey{Namespace: myKind.Namespace, Name: myKind.Name}, persisted); err != nil {
+				t.Fatalf("failed to get MyKind: %v", err)
+			}
+			if persisted.Status.ObservedGeneration != myKind.Generation {
+				t.Errorf("expected observedGeneration %d, got %d", myKind.Generation, persisted.Status.ObservedGeneration)
+			}
+			if persisted.Status.DeploymentName != deployment.Name {
+				t.Errorf("expected deploymentName %q, got %q", deployment.Name, persisted.Status.DeploymentName)
+			}
+			if myKind.Status.DeploymentName != deployment.Name {
+				t.Errorf("expected status of the MyKind to be updated, got deploymentName %q", myKind.Status.DeploymentName)
+			}
+		})
+	}
+}
+
+func TestSyncStatusOnlyWritesChanges(t *testing.T) {
+	ctx := context.Background()
+	myKind := newTestStatusMyKind()
+	r, c := newTestStatusReconciler(t, 0, myKind.DeepCopy())
+
+	if err := r.syncStatus(ctx, r.Log, myKind, observedState{}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.statusPatches != 1 {
+		t.Fatalf("expected the first sync to patch the status, got %d patches", c.statusPatches)
+	}
+
+	if err := r.syncStatus(ctx, r.Log, myKind, observedState{}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.statusPatches != 1 {
+		t.Errorf("expected an unchanged status not to be written, got %d patches", c.statusPatches)
+	}
+
+	if err := r.syncStatus(ctx, r.Log, myKind, observedState{}, errors.New("failed")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.statusPatches != 2 {
+		t.Errorf("expected a changed status to be written, got %d patches", c.statusPatches)
+	}
+	if got := conditionStatus(myKind.Status.Conditions, mygroupv1.MyKindDegraded); got != core.ConditionTrue {
+		t.Errorf("expected Degraded condition to be True, got %q", got)
+	}
+}
+
+func TestSyncStatusTransitionTimes(t *testing.T) {
+	ctx := context.Background()
+	myKind := newTestStatusMyKind()
+	r, _ := newTestStatusReconciler(t, 0, myKind.DeepCopy())
+	start := time.Date(2019, time.June, 3, 10, 0, 0, 0, time.UTC)
+	fakeClock := clock.NewFakeClock(start)
+	r.Clock = fakeClock
+
+	if err := r.syncStatus(ctx, r.Log, myKind, observedState{}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fakeClock.Step(time.Hour)
+	if err := r.syncStatus(ctx, r.Log, myKind, observedState{}, errors.New("failed")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, c := range myKind.Status.Conditions {
+		want := start
+		if c.Type == mygroupv1.MyKindDegraded {
+			want = start.Add(time.Hour)
+		}
+		if !c.LastTransitionTime.Time.Equal(want) {
+			t.Errorf("expected %s condition to have last transitioned at %v, got %v", c.Type, want, c.LastTransitionTime.Time)
+		}
+	}
+}
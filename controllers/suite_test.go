@@ -36,6 +36,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	// +kubebuilder:scaffold:imports
 
+	mygroupv1 "jetstack.io/example-controller/api/v1"
 	mygroupv1beta1 "jetstack.io/example-controller/api/v1beta1"
 )
 
@@ -60,6 +61,9 @@ var _ = BeforeSuite(func(done Done) {
 	By("bootstrapping test environment")
 	testEnv = &envtest.Environment{
 		CRDDirectoryPaths: []string{filepath.Join("..", "config", "crd", "bases")},
+		// Server-side apply is an alpha feature in the version of
+		// kube-apiserver used by the tests.
+		KubeAPIServerFlags: append([]string{"--feature-gates=ServerSideApply=true"}, envtest.DefaultKubeAPIServerFlags...),
 	}
 
 	var err error
@@ -70,6 +74,9 @@ var _ = BeforeSuite(func(done Done) {
 	err = mygroupv1beta1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = mygroupv1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	// +kubebuilder:scaffold:scheme
 
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
@@ -91,7 +98,9 @@ var _ = AfterSuite(func() {
 // * starting the 'MyKindReconciler'
 // * stopping the 'MyKindReconciler" after the test ends
 // Call this function at the start of each of your tests.
-func SetupTest(ctx context.Context) *core.Namespace {
+// The reconciler forces ownership of conflicting fields, as it does by
+// default, unless opts modify it.
+func SetupTest(ctx context.Context, opts ...func(*MyKindReconciler)) *core.Namespace {
 	var stopCh chan struct{}
 	ns := &core.Namespace{}
 
@@ -108,9 +117,13 @@ func SetupTest(ctx context.Context) *core.Namespace {
 		Expect(err).NotTo(HaveOccurred(), "failed to create manager")
 
 		controller := &MyKindReconciler{
-			Client:   mgr.GetClient(),
-			Log:      logf.Log,
-			Recorder: mgr.GetEventRecorderFor("mykind-controller"),
+			Client:         mgr.GetClient(),
+			Log:            logf.Log,
+			Recorder:       mgr.GetEventRecorderFor("mykind-controller"),
+			ForceOwnership: true,
+		}
+		for _, opt := range opts {
+			opt(controller)
 		}
 		err = controller.SetupWithManager(mgr)
 		Expect(err).NotTo(HaveOccurred(), "failed to setup controller")
@@ -0,0 +1,478 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mygroupv1 "jetstack.io/example-controller/api/v1"
+)
+
+// workloadKinds are the kinds of workload that may be created for a MyKind.
+var workloadKinds = []mygroupv1.WorkloadKind{
+	mygroupv1.WorkloadKindDeployment,
+	mygroupv1.WorkloadKindStatefulSet,
+	mygroupv1.WorkloadKindDaemonSet,
+}
+
+// workloadObject is implemented by the Deployment, StatefulSet and
+// DaemonSet types.
+type workloadObject interface {
+	runtime.Object
+	metav1.Object
+}
+
+// reconcileWorkload ensures that the workload of the kind given by
+// spec.workloadKind and the Deployments of the components of the given
+// MyKind exist and are up to date, and cleans up any workloads previously
+// created for it.
+// When spec.workloadKind is changed, workloads of the previous kind are only
+// deleted once the new workload has been rolled out, so that the pods of
+// the MyKind remain available throughout the migration.
+// It returns the workloads as last observed or written by the controller.
+func (r *MyKindReconciler) reconcileWorkload(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind) (observedState, ctrl.Result, error) {
+	var observed observedState
+
+	kind := workloadKind(*myKind)
+	log = log.WithValues("workload_kind", kind, "deployment_name", myKind.Spec.Deployment.Name)
+
+	if err := r.cleanupOwnedWorkloads(ctx, log, myKind, false); err != nil {
+		log.Error(err, "failed to clean up old workload resources for this MyKind")
+		return observed, ctrl.Result{}, err
+	}
+
+	configHash, err := r.computeConfigHash(ctx, myKind)
+	if err != nil {
+		log.Error(err, "failed to compute hash of referenced ConfigMaps and Secrets")
+		return observed, ctrl.Result{}, err
+	}
+
+	var result ctrl.Result
+	desired := buildWorkload(*myKind, configHash)
+	switch {
+	case canaryEnabled(*myKind):
+		observed, result, err = r.reconcileCanary(ctx, log, myKind, desired.(*apps.Deployment))
+	case blueGreenEnabled(*myKind):
+		observed, result, err = r.reconcileBlueGreen(ctx, log, myKind, desired.(*apps.Deployment))
+	default:
+		observed.workload, result, err = r.reconcileWorkloadObject(ctx, log, myKind, desired)
+	}
+	if err != nil {
+		return observed, result, err
+	}
+
+	var componentsResult ctrl.Result
+	observed.components, componentsResult, err = r.reconcileComponents(ctx, log, myKind)
+	result.Requeue = result.Requeue || componentsResult.Requeue
+	if err != nil {
+		return observed, result, err
+	}
+
+	if summary := summarizeWorkload(observed.workload); summary == nil || !summary.complete {
+		log.Info("waiting for workload to be rolled out before deleting workloads of other kinds")
+		return observed, result, nil
+	}
+
+	if err := r.cleanupOwnedWorkloads(ctx, log, myKind, true); err != nil {
+		log.Error(err, "failed to clean up workload resources of other kinds for this MyKind")
+		return observed, result, err
+	}
+
+	return observed, result, nil
+}
+
+// reconcileWorkloadObject ensures that the given desired workload exists and
+// is up to date.
+// It returns the workload as last observed or written by the controller,
+// which will be nil if it could not be retrieved or created.
+func (r *MyKindReconciler) reconcileWorkloadObject(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, desired workloadObject) (workloadObject, ctrl.Result, error) {
+	kind := kindOf(desired)
+
+	log.Info("checking if an existing workload exists for this resource")
+	existing := newWorkload(kind)
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: desired.GetNamespace(), Name: desired.GetName()}, existing)
+	if apierrors.IsNotFound(err) {
+		log.Info("could not find existing workload for MyKind, creating one...")
+
+		if err := r.apply(ctx, desired); err != nil {
+			log.Error(err, "failed to create workload resource")
+			return nil, ctrl.Result{}, err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Created", "Created %s %q", workloadKindName(kind), desired.GetName())
+		log.Info("created workload resource for MyKind")
+		return desired, ctrl.Result{}, nil
+	}
+	if err != nil {
+		log.Error(err, "failed to get workload for MyKind resource")
+		return nil, ctrl.Result{}, err
+	}
+
+	if !metav1.IsControlledBy(existing, myKind) {
+		deployment, ok := existing.(*apps.Deployment)
+		if !ok {
+			return nil, ctrl.Result{}, &deploymentNameConflictError{
+				reason:  "AdoptionNotSupported",
+				message: fmt.Sprintf("%s %q already exists and is not controlled by this MyKind, and only Deployments may be adopted", kind, existing.GetName()),
+			}
+		}
+
+		log.Info("existing Deployment is not controlled by this MyKind, attempting to adopt it", "adoption_policy", myKind.Spec.AdoptionPolicy)
+		if err := r.adoptDeployment(ctx, log, myKind, deployment); err != nil {
+			log.Error(err, "failed to adopt Deployment")
+			return nil, ctrl.Result{}, err
+		}
+	}
+
+	log.Info("checking the workload for drift")
+	if !selectorCompatible(workloadSelector(existing), workloadTemplate(desired)) {
+		// The selector of a workload is immutable, so the only way to
+		// correct it is to delete the workload and create it again.
+		log.Info("workload selector has drifted, deleting workload so that it is recreated")
+
+		if err := r.Client.Delete(ctx, existing); err != nil {
+			log.Error(err, "failed to delete workload with drifted selector")
+			return existing, ctrl.Result{}, err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "DriftCorrected", "Deleted %s %q to correct drift in spec.selector", workloadKindName(kind), existing.GetName())
+
+		return nil, ctrl.Result{Requeue: true}, nil
+	}
+
+	scaled := false
+	if replicas := workloadReplicas(existing); replicas != nil {
+		// If autoscaling is enabled the replica count is managed by the
+		// HorizontalPodAutoscaler created for this MyKind.
+		scaledExternally := myKind.Spec.Autoscaling != nil
+		if !scaledExternally {
+			scaledExternally, err = r.replicasManagedExternally(ctx, existing.GetNamespace(), string(kind), existing.GetName())
+			if err != nil {
+				log.Error(err, "failed to check if workload is scaled by a HorizontalPodAutoscaler")
+				return existing, ctrl.Result{}, err
+			}
+		}
+
+		desiredReplicas := workloadReplicas(desired)
+		if scaledExternally {
+			log.Info("workload is scaled by a HorizontalPodAutoscaler, not managing replica count", "replica_count", *replicas)
+			// The current replica count is applied rather than omitted, as
+			// giving up ownership of the field would reset it to its default.
+			*desiredReplicas = *replicas
+		} else if *replicas != *desiredReplicas {
+			log.Info("updating replica count", "old_count", *replicas, "new_count", *desiredReplicas)
+			scaled = true
+		} else {
+			log.Info("replica count up to date", "replica_count", *replicas)
+		}
+	}
+
+	corrected := correctWorkloadDrift(existing.DeepCopyObject().(workloadObject), desired)
+	if !scaled && len(corrected) == 0 {
+		log.Info("workload up to date")
+		return existing, ctrl.Result{}, nil
+	}
+
+	log.Info("applying workload", "scaled", scaled, "fields", corrected)
+	if err := r.apply(ctx, desired); err != nil {
+		log.Error(err, "failed to apply workload")
+		return existing, ctrl.Result{}, err
+	}
+
+	if scaled {
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Scaled", "Scaled %s %q to %d replicas", workloadKindName(kind), desired.GetName(), *workloadReplicas(desired))
+	}
+	if len(corrected) > 0 {
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "DriftCorrected", "Corrected drift in %s %q: %s", workloadKindName(kind), desired.GetName(), strings.Join(corrected, ", "))
+	}
+
+	return desired, ctrl.Result{}, nil
+}
+
+// cleanupOwnedWorkloads will Delete any existing workload resources that
+// were created for the given MyKind that are no longer desired for it, such
+// as those of a previous myKind.spec.deployment.name or of components that
+// have been removed from myKind.spec.components.
+// Workloads of another kind than myKind.spec.workloadKind that were not
+// created for a component are only deleted if replaced is true, as they
+// are replaced by the workload of the current kind. The same goes for the
+// Deployments of a blue/green rollout once it is no longer used, and for
+// the Deployment that was used before it.
+func (r *MyKindReconciler) cleanupOwnedWorkloads(ctx context.Context, log logr.Logger, myKind *mygroupv1.MyKind, replaced bool) error {
+	log.Info("finding existing workloads for MyKind resource", "all_kinds", replaced)
+
+	workloads, err := r.listOwnedWorkloads(ctx, myKind, workloadKinds)
+	if err != nil {
+		return err
+	}
+
+	current := workloadKind(*myKind)
+	desired := desiredWorkloads(*myKind)
+
+	deleted := 0
+	for _, workload := range workloads {
+		kind := kindOf(workload)
+		if desired[workloadRef{kind: kind, name: workload.GetName()}] {
+			// If this workload's kind and name match those of a workload
+			// desired for the MyKind resource then do not delete it.
+			continue
+		}
+		if !replaced && !isComponentWorkload(workload) && (kind != current || isColorWorkload(workload) != blueGreenEnabled(*myKind)) {
+			continue
+		}
+
+		if err := r.Client.Delete(ctx, workload); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "failed to delete workload resource", "kind", kind, "name", workload.GetName())
+			return err
+		}
+
+		r.Recorder.Eventf(myKind, core.EventTypeNormal, "Deleted", "Deleted %s %q", workloadKindName(kind), workload.GetName())
+		deleted++
+	}
+
+	log.Info("finished cleaning up old workload resources", "number_deleted", deleted)
+
+	return nil
+}
+
+// workloadRef identifies a workload by its kind and name.
+type workloadRef struct {
+	kind mygroupv1.WorkloadKind
+	name string
+}
+
+// desiredWorkloads returns the workloads that may exist for the given
+// MyKind: the workload of the kind given by spec.workloadKind, or its blue
+// and green Deployments if it is rolled out with them, its canary
+// Deployment if it is rolled out with one, and the Deployment of each of
+// its components.
+func desiredWorkloads(myKind mygroupv1.MyKind) map[workloadRef]bool {
+	desired := map[workloadRef]bool{}
+	if blueGreenEnabled(myKind) {
+		// The Deployment of the colour that is not active is deleted by
+		// reconcileBlueGreen once it is no longer needed.
+		for _, color := range []mygroupv1.DeploymentColor{mygroupv1.DeploymentColorBlue, mygroupv1.DeploymentColorGreen} {
+			desired[workloadRef{kind: mygroupv1.WorkloadKindDeployment, name: colorDeploymentName(myKind, color)}] = true
+		}
+	} else {
+		desired[workloadRef{kind: workloadKind(myKind), name: myKind.Spec.Deployment.Name}] = true
+	}
+	if canaryEnabled(myKind) {
+		// The canary Deployment is deleted by reconcileCanary once it is no
+		// longer needed.
+		desired[workloadRef{kind: mygroupv1.WorkloadKindDeployment, name: canaryDeploymentName(myKind)}] = true
+	}
+	for _, component := range myKind.Spec.Components {
+		desired[workloadRef{kind: mygroupv1.WorkloadKindDeployment, name: component.Deployment.Name}] = true
+	}
+	return desired
+}
+
+// listOwnedWorkloads returns the workloads of the given kinds that were
+// created for the given MyKind.
+func (r *MyKindReconciler) listOwnedWorkloads(ctx context.Context, myKind *mygroupv1.MyKind, kinds []mygroupv1.WorkloadKind) ([]workloadObject, error) {
+	var workloads []workloadObject
+	for _, kind := range kinds {
+		// List all workload resources of this kind owned by this MyKind
+		list, ownerKey := newWorkloadList(kind)
+		if err := r.List(ctx, list, client.InNamespace(myKind.Namespace), client.MatchingField(ownerKey, myKind.Name)); err != nil {
+			return nil, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			workloads = append(workloads, item.(workloadObject))
+		}
+	}
+	return workloads, nil
+}
+
+// buildWorkload renders the workload of the kind given by spec.workloadKind
+// for the given MyKind. configHash is the hash of the ConfigMaps and Secrets
+// it references, and is recorded on the pod template if it is not empty.
+func buildWorkload(myKind mygroupv1.MyKind, configHash string) workloadObject {
+	switch workloadKind(myKind) {
+	case mygroupv1.WorkloadKindStatefulSet:
+		return buildStatefulSet(myKind, configHash)
+	case mygroupv1.WorkloadKindDaemonSet:
+		return buildDaemonSet(myKind, configHash)
+	default:
+		return buildDeployment(myKind, configHash)
+	}
+}
+
+// buildStatefulSet renders the StatefulSet for the given MyKind.
+// Its governing service is the headless Service created for the MyKind,
+// which has the same name.
+func buildStatefulSet(myKind mygroupv1.MyKind, configHash string) *apps.StatefulSet {
+	statefulSet := apps.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apps.SchemeGroupVersion.String(),
+			Kind:       "StatefulSet",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            myKind.Spec.Deployment.Name,
+			Namespace:       myKind.Namespace,
+			Labels:          deploymentLabels(myKind),
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(&myKind, mygroupv1.GroupVersion.WithKind("MyKind"))},
+		},
+		Spec: apps.StatefulSetSpec{
+			Replicas: initialReplicas(myKind),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: deploymentLabels(myKind),
+			},
+			ServiceName: myKind.Spec.Deployment.Name,
+			Template:    *buildPodTemplate(myKind, configHash),
+		},
+	}
+	return &statefulSet
+}
+
+// buildDaemonSet renders the DaemonSet for the given MyKind.
+func buildDaemonSet(myKind mygroupv1.MyKind, configHash string) *apps.DaemonSet {
+	daemonSet := apps.DaemonSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apps.SchemeGroupVersion.String(),
+			Kind:       "DaemonSet",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            myKind.Spec.Deployment.Name,
+			Namespace:       myKind.Namespace,
+			Labels:          deploymentLabels(myKind),
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(&myKind, mygroupv1.GroupVersion.WithKind("MyKind"))},
+		},
+		Spec: apps.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: deploymentLabels(myKind),
+			},
+			Template: *buildPodTemplate(myKind, configHash),
+		},
+	}
+	return &daemonSet
+}
+
+// workloadKind returns the kind of workload managed for the given MyKind,
+// which may not have had defaults applied.
+func workloadKind(myKind mygroupv1.MyKind) mygroupv1.WorkloadKind {
+	if myKind.Spec.WorkloadKind == "" {
+		return mygroupv1.DefaultWorkloadKind
+	}
+	return myKind.Spec.WorkloadKind
+}
+
+// workloadKindName returns the name of the given kind of workload as used
+// in events and messages.
+func workloadKindName(kind mygroupv1.WorkloadKind) string {
+	switch kind {
+	case mygroupv1.WorkloadKindStatefulSet:
+		return "stateful set"
+	case mygroupv1.WorkloadKindDaemonSet:
+		return "daemon set"
+	default:
+		return "deployment"
+	}
+}
+
+// newWorkload returns an empty object of the given kind of workload.
+func newWorkload(kind mygroupv1.WorkloadKind) workloadObject {
+	switch kind {
+	case mygroupv1.WorkloadKindStatefulSet:
+		return &apps.StatefulSet{}
+	case mygroupv1.WorkloadKindDaemonSet:
+		return &apps.DaemonSet{}
+	default:
+		return &apps.Deployment{}
+	}
+}
+
+// newWorkloadList returns an empty list of the given kind of workload, and
+// the key of the field index of its items by controlling MyKind.
+func newWorkloadList(kind mygroupv1.WorkloadKind) (runtime.Object, string) {
+	switch kind {
+	case mygroupv1.WorkloadKindStatefulSet:
+		return &apps.StatefulSetList{}, statefulSetOwnerKey
+	case mygroupv1.WorkloadKindDaemonSet:
+		return &apps.DaemonSetList{}, daemonSetOwnerKey
+	default:
+		return &apps.DeploymentList{}, deploymentOwnerKey
+	}
+}
+
+// kindOf returns the kind of the given workload.
+func kindOf(workload workloadObject) mygroupv1.WorkloadKind {
+	switch workload.(type) {
+	case *apps.StatefulSet:
+		return mygroupv1.WorkloadKindStatefulSet
+	case *apps.DaemonSet:
+		return mygroupv1.WorkloadKindDaemonSet
+	default:
+		return mygroupv1.WorkloadKindDeployment
+	}
+}
+
+// workloadSelector returns the selector of the given workload.
+func workloadSelector(workload workloadObject) *metav1.LabelSelector {
+	switch w := workload.(type) {
+	case *apps.Deployment:
+		return w.Spec.Selector
+	case *apps.StatefulSet:
+		return w.Spec.Selector
+	case *apps.DaemonSet:
+		return w.Spec.Selector
+	}
+	return nil
+}
+
+// workloadTemplate returns the pod template of the given workload, which may
+// be modified in place.
+func workloadTemplate(workload workloadObject) *core.PodTemplateSpec {
+	switch w := workload.(type) {
+	case *apps.Deployment:
+		return &w.Spec.Template
+	case *apps.StatefulSet:
+		return &w.Spec.Template
+	case *apps.DaemonSet:
+		return &w.Spec.Template
+	}
+	return nil
+}
+
+// workloadReplicas returns the replica count of the given workload, which
+// may be modified in place, or nil if its kind cannot be scaled.
+func workloadReplicas(workload workloadObject) *int32 {
+	switch w := workload.(type) {
+	case *apps.Deployment:
+		return w.Spec.Replicas
+	case *apps.StatefulSet:
+		return w.Spec.Replicas
+	}
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"flag"
 	"os"
 
+	mygroupv1 "jetstack.io/example-controller/api/v1"
 	mygroupv1beta1 "jetstack.io/example-controller/api/v1beta1"
 	"jetstack.io/example-controller/controllers"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -39,15 +40,19 @@ func init() {
 	_ = clientgoscheme.AddToScheme(scheme)
 
 	_ = mygroupv1beta1.AddToScheme(scheme)
+	_ = mygroupv1.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var forceOwnership bool
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&forceOwnership, "force-ownership", true,
+		"Take ownership of fields of managed resources that were last set by another field manager, such as fields edited with kubectl. If disabled, drift in such fields is not corrected and the resources are not reconciled until the conflict is resolved.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.Logger(true))
@@ -63,13 +68,24 @@ func main() {
 	}
 
 	if err = (&controllers.MyKindReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("controllers").WithName("MyKind"),
-		Recorder: mgr.GetEventRecorderFor("mykind-controller"),
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controllers").WithName("MyKind"),
+		Recorder:       mgr.GetEventRecorderFor("mykind-controller"),
+		ForceOwnership: forceOwnership,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "MyKind")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&mygroupv1beta1.MyKind{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "MyKind", "version", "v1beta1")
+			os.Exit(1)
+		}
+		if err = (&mygroupv1.MyKind{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "MyKind", "version", "v1")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
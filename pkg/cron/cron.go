@@ -0,0 +1,255 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cron parses cron expressions in the standard five field format
+// and computes the times at which they fire.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchYears is how many years ahead and behind of a given time the
+// activations of a schedule are searched for. A schedule that does not fire
+// within it, such as one for the 30th of February, is treated as never
+// firing.
+const searchYears = 5
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	minutes, hours, days, months, weekdays uint64
+
+	// daysRestricted and weekdaysRestricted are true if the day of month
+	// and day of week fields are not "*". If both are, a time matches if
+	// either of them matches, as in the original cron.
+	daysRestricted, weekdaysRestricted bool
+}
+
+// field describes one of the fields of a cron expression.
+type field struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var (
+	minuteField  = field{name: "minute", min: 0, max: 59}
+	hourField    = field{name: "hour", min: 0, max: 23}
+	dayField     = field{name: "day of month", min: 1, max: 31}
+	monthField   = field{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	weekdayField = field{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+// descriptors are the shorthands that may be used in place of the five
+// fields.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a cron expression made up of minute, hour, day of month,
+// month and day of week fields, or one of the descriptors such as @daily.
+// Each field is "*", a value, a range of values "a-b" or a comma separated
+// list of them, and any but a single value may be followed by "/n" to
+// select every nth value. Months and days of the week may be given by the
+// first three letters of their names, and Sunday may be given as 0 or 7.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if descriptor, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = descriptor
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, found %d: %q", len(fields), spec)
+	}
+
+	s := &Schedule{
+		daysRestricted:     fields[2] != "*",
+		weekdaysRestricted: fields[4] != "*",
+	}
+	var err error
+	if s.minutes, err = minuteField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if s.hours, err = hourField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if s.days, err = dayField.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if s.months, err = monthField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if s.weekdays, err = weekdayField.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	// Sunday may be given as either 0 or 7.
+	if s.weekdays&(1<<7) != 0 {
+		s.weekdays |= 1
+	}
+
+	return s, nil
+}
+
+// parse returns the set of values selected by the given value of the field
+// as a bit set.
+func (f field) parse(value string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(value, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field: %q", f.name, part)
+			}
+			step = n
+		}
+
+		var start, end int
+		switch {
+		case rangePart == "*":
+			start, end = f.min, f.max
+		case strings.Contains(rangePart, "-"):
+			i := strings.Index(rangePart, "-")
+			var err error
+			if start, err = f.value(rangePart[:i]); err != nil {
+				return 0, err
+			}
+			if end, err = f.value(rangePart[i+1:]); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range in %s field: %q", f.name, part)
+			}
+		default:
+			var err error
+			if start, err = f.value(rangePart); err != nil {
+				return 0, err
+			}
+			end = start
+			if step != 1 {
+				return 0, fmt.Errorf("step given for a single value in %s field: %q", f.name, part)
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value parses a single value of the field, given as a number or a name.
+func (f field) value(value string) (int, error) {
+	for i, name := range f.names {
+		if strings.ToLower(value) == name {
+			return f.min + i, nil
+		}
+	}
+
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value in %s field: %q", f.name, value)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d in %s field is outside of the range %d-%d", v, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first time after t at which the schedule fires, in the
+// location of t. It returns the zero time if the schedule does not fire in
+// the next five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + searchYears
+
+	for t.Year() <= limit {
+		var next time.Time
+		switch {
+		case !has(s.months, int(t.Month())):
+			next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !has(s.hours, t.Hour()):
+			next = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !has(s.minutes, t.Minute()):
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+		// Around a daylight saving time change the wall clock time that is
+		// moved to may not exist, in which case the search moves on by a
+		// minute so that it always makes progress.
+		if !next.After(t) {
+			next = t.Add(time.Minute)
+		}
+		t = next
+	}
+
+	return time.Time{}
+}
+
+// Prev returns the last time at or before t at which the schedule fired, in
+// the location of t. It returns the zero time if the schedule did not fire
+// in the last five years.
+func (s *Schedule) Prev(t time.Time) time.Time {
+	// The schedule is searched for over a window before t that is doubled
+	// until it contains an activation, so that frequent schedules are not
+	// stepped through over a long window.
+	limit := t.AddDate(-searchYears, 0, 0)
+	for window := time.Minute; ; window *= 2 {
+		start := t.Add(-window)
+		if start.Before(limit) {
+			start = limit
+		}
+
+		prev := time.Time{}
+		for next := s.Next(start.Add(-time.Nanosecond)); !next.IsZero() && !next.After(t); next = s.Next(next) {
+			prev = next
+		}
+		if !prev.IsZero() || !start.After(limit) {
+			return prev
+		}
+	}
+}
+
+// dayMatches returns true if the day of t matches the day of month and day
+// of week fields of the schedule.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	day := has(s.days, t.Day())
+	weekday := has(s.weekdays, int(t.Weekday()))
+	if s.daysRestricted && s.weekdaysRestricted {
+		return day || weekday
+	}
+	return day && weekday
+}
+
+// has returns true if the given value is in the given bit set.
+func has(bits uint64, value int) bool {
+	return bits&(1<<uint(value)) != 0
+}
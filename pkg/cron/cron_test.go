@@ -0,0 +1,151 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"0 20 * * 1-5",
+		"*/15 8-18/2 1,15 jan-mar SUN",
+		"0 0 * * 7",
+		"@daily",
+	}
+	for _, spec := range valid {
+		if _, err := Parse(spec); err != nil {
+			t.Errorf("expected %q to be valid, got error: %v", spec, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"5/15 * * * *",
+		"* * * foo *",
+		"@fortnightly",
+	}
+	for _, spec := range invalid {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("expected %q to be invalid", spec)
+		}
+	}
+}
+
+func TestNextAndPrev(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	at := func(loc *time.Location, year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name     string
+		spec     string
+		t        time.Time
+		wantNext time.Time
+		wantPrev time.Time
+	}{
+		{
+			name:     "every minute",
+			spec:     "* * * * *",
+			t:        at(time.UTC, 2019, 6, 3, 10, 30).Add(20 * time.Second),
+			wantNext: at(time.UTC, 2019, 6, 3, 10, 31),
+			wantPrev: at(time.UTC, 2019, 6, 3, 10, 30),
+		},
+		{
+			name:     "weekday evenings from a Friday evening",
+			spec:     "0 20 * * mon-fri",
+			t:        at(time.UTC, 2019, 6, 7, 21, 0),
+			wantNext: at(time.UTC, 2019, 6, 10, 20, 0),
+			wantPrev: at(time.UTC, 2019, 6, 7, 20, 0),
+		},
+		{
+			name:     "activation at the given time",
+			spec:     "0 8 * * *",
+			t:        at(time.UTC, 2019, 6, 3, 8, 0),
+			wantNext: at(time.UTC, 2019, 6, 4, 8, 0),
+			wantPrev: at(time.UTC, 2019, 6, 3, 8, 0),
+		},
+		{
+			name:     "day of month or day of week",
+			spec:     "0 0 1 * sun",
+			t:        at(time.UTC, 2019, 6, 3, 0, 0),
+			wantNext: at(time.UTC, 2019, 6, 9, 0, 0),
+			wantPrev: at(time.UTC, 2019, 6, 2, 0, 0),
+		},
+		{
+			name:     "yearly",
+			spec:     "@yearly",
+			t:        at(time.UTC, 2019, 6, 3, 0, 0),
+			wantNext: at(time.UTC, 2020, 1, 1, 0, 0),
+			wantPrev: at(time.UTC, 2019, 1, 1, 0, 0),
+		},
+		{
+			name:     "leap day",
+			spec:     "0 0 29 2 *",
+			t:        at(time.UTC, 2019, 6, 3, 0, 0),
+			wantNext: at(time.UTC, 2020, 2, 29, 0, 0),
+			wantPrev: at(time.UTC, 2016, 2, 29, 0, 0),
+		},
+		{
+			name: "never",
+			spec: "0 0 30 2 *",
+			t:    at(time.UTC, 2019, 6, 3, 0, 0),
+		},
+		{
+			name:     "local time",
+			spec:     "0 8 * * *",
+			t:        at(london, 2019, 6, 3, 7, 30),
+			wantNext: at(london, 2019, 6, 3, 8, 0),
+			wantPrev: at(london, 2019, 6, 2, 8, 0),
+		},
+		{
+			name:     "skipped by daylight saving time",
+			spec:     "30 1 * * *",
+			t:        at(london, 2019, 3, 31, 0, 0),
+			wantNext: at(london, 2019, 4, 1, 1, 30),
+			wantPrev: at(london, 2019, 3, 30, 1, 30),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := s.Next(tt.t); !got.Equal(tt.wantNext) {
+				t.Errorf("expected next activation %v, got %v", tt.wantNext, got)
+			}
+			if got := s.Prev(tt.t); !got.Equal(tt.wantPrev) {
+				t.Errorf("expected previous activation %v, got %v", tt.wantPrev, got)
+			}
+		})
+	}
+}